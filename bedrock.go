@@ -6,28 +6,60 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/dingdinglz/adk-go-bedrock/internal/bedrockclient"
+	"github.com/tmc/langchaingo/llms"
 	"google.golang.org/adk/model"
 	"google.golang.org/genai"
 )
 
 const defaultMaxTokens = 4096
 
+const defaultEmptyContentsPlaceholder = "Handle the requests as specified in the System Instruction."
+
 type bedrockModel struct {
 	modelName string
 	client    *bedrockclient.Client
 	maxTokens int
+	// useConverse selects the Converse API backend instead of InvokeModel.
+	useConverse bool
+
+	defaultConfig            *genai.GenerateContentConfig
+	providerName             string
+	retryPolicy              *RetryPolicy
+	hooks                    Hooks
+	guardrail                *GuardrailConfig
+	anthropicBeta            []string
+	cachePolicy              *CachePolicy
+	emptyContentsPlaceholder string
+	objectGetter             ObjectGetter
+	s3BucketOwner            string
+	normalizeImages          bool
+	urlFetcher               *URLFetcher
 }
 
-func NewModel(bedrockClient *bedrockruntime.Client, modelName string, maxTokens int) model.LLM {
+func NewModel(bedrockClient *bedrockruntime.Client, modelName string, maxTokens int, opts ...Option) model.LLM {
 	if maxTokens <= 0 {
 		maxTokens = defaultMaxTokens
 	}
 
-	return &bedrockModel{
-		client:    bedrockclient.NewClient(bedrockClient),
-		modelName: modelName,
-		maxTokens: maxTokens,
+	m := &bedrockModel{
+		client:                   bedrockclient.NewClient(bedrockClient),
+		modelName:                modelName,
+		maxTokens:                maxTokens,
+		emptyContentsPlaceholder: defaultEmptyContentsPlaceholder,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.providerName != "" {
+		m.client.PinProvider(modelName, m.providerName)
+	}
+	if m.objectGetter != nil {
+		m.client.SetObjectGetter(m.objectGetter)
+	}
+	if m.urlFetcher != nil {
+		m.client.SetURLFetcher(m.urlFetcher)
 	}
+	return m
 }
 
 func (m *bedrockModel) Name() string {
@@ -49,7 +81,74 @@ func (m *bedrockModel) GenerateContent(ctx context.Context, req *model.LLMReques
 func (m *bedrockModel) maybeAppendUserContent(req *model.LLMRequest) {
 	if len(req.Contents) == 0 {
 		req.Contents = append(req.Contents,
-			genai.NewContentFromText("Handle the requests as specified in the System Instruction.", "user"))
+			genai.NewContentFromText(m.emptyContentsPlaceholder, "user"))
 		return
 	}
 }
+
+// createCompletion sends the converted request to the configured backend,
+// running the hooks around and retrying according to the retry policy.
+func (m *bedrockModel) createCompletion(ctx context.Context, msgs []bedrockclient.Message, options llms.CallOptions) (*llms.ContentResponse, error) {
+	policy := RetryPolicy{MaxAttempts: 1}
+	if m.retryPolicy != nil {
+		policy = *m.retryPolicy
+	}
+
+	// Once a partial response reached the caller a retry would duplicate
+	// it, so streamed calls are only retried before their first chunk.
+	streamed := false
+	if streamingFunc := options.StreamingFunc; streamingFunc != nil {
+		options.StreamingFunc = func(ctx context.Context, chunk []byte) error {
+			streamed = true
+			return streamingFunc(ctx, chunk)
+		}
+	}
+	if reasoningFunc := options.StreamingReasoningFunc; reasoningFunc != nil {
+		options.StreamingReasoningFunc = func(ctx context.Context, reasoningChunk, chunk []byte) error {
+			streamed = true
+			return reasoningFunc(ctx, reasoningChunk, chunk)
+		}
+	}
+	if eventFunc, _ := options.Metadata[bedrockclient.MetadataStreamEventFunc].(bedrockclient.StreamEventFunc); eventFunc != nil {
+		bedrockclient.SetMetadata(&options, bedrockclient.MetadataStreamEventFunc, bedrockclient.StreamEventFunc(func(ctx context.Context, event bedrockclient.StreamEvent) error {
+			streamed = true
+			return eventFunc(ctx, event)
+		}))
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := m.callOnce(ctx, msgs, options)
+		if err == nil || streamed || attempt >= policy.maxAttempts() || !policy.retryable(err) {
+			return resp, err
+		}
+		if m.hooks.OnRetry != nil {
+			m.hooks.OnRetry(ctx, attempt+1, err)
+		}
+		if sleepErr := sleep(ctx, policy.backoff(attempt)); sleepErr != nil {
+			return nil, err
+		}
+	}
+}
+
+func (m *bedrockModel) callOnce(ctx context.Context, msgs []bedrockclient.Message, options llms.CallOptions) (*llms.ContentResponse, error) {
+	if m.hooks.BeforeCall != nil {
+		if err := m.hooks.BeforeCall(ctx, m.modelName, msgs, &options); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		resp *llms.ContentResponse
+		err  error
+	)
+	if m.useConverse {
+		resp, err = m.client.CreateConverseCompletion(ctx, m.modelName, msgs, options)
+	} else {
+		resp, err = m.client.CreateCompletion(ctx, m.modelName, msgs, options)
+	}
+
+	if m.hooks.AfterCall != nil {
+		m.hooks.AfterCall(ctx, m.modelName, resp, err)
+	}
+	return resp, err
+}
@@ -17,7 +17,7 @@ func (m *bedrockModel) generate(ctx context.Context, req *model.LLMRequest) (*mo
 	}
 
 	// 请求
-	originResp, err := m.client.CreateCompletion(ctx, m.modelName, msgs, options)
+	originResp, err := m.createCompletion(ctx, msgs, options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call model: %w", err)
 	}
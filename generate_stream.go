@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"iter"
 
+	"github.com/dingdinglz/adk-go-bedrock/internal/bedrockclient"
 	"github.com/dingdinglz/adk-go-bedrock/internal/converters"
 	"google.golang.org/adk/model"
 	"google.golang.org/genai"
@@ -32,13 +33,51 @@ func (m *bedrockModel) generateStream(ctx context.Context, req *model.LLMRequest
 					},
 				},
 			}, nil) {
-				return errors.New("yield break")
+				return bedrockclient.ErrStreamStopped
 			}
 
 			return nil
 		}
+		options.StreamingReasoningFunc = func(ctx context.Context, reasoningChunk, chunk []byte) error {
+			if len(reasoningChunk) == 0 {
+				return nil
+			}
+			if !yield(&model.LLMResponse{
+				Partial: true,
+				Content: &genai.Content{
+					Role: "model",
+					Parts: []*genai.Part{
+						{
+							Text:    string(reasoningChunk),
+							Thought: true,
+						},
+					},
+				},
+			}, nil) {
+				return bedrockclient.ErrStreamStopped
+			}
+
+			return nil
+		}
+
+		// Tool call progress and usage are reported as they stream in.
+		seenArgs := make(map[string]map[string]bool)
+		bedrockclient.SetMetadata(&options, bedrockclient.MetadataStreamEventFunc, bedrockclient.StreamEventFunc(func(ctx context.Context, event bedrockclient.StreamEvent) error {
+			resp, err := converters.StreamEventToLLMResponse(event, seenArgs)
+			if err != nil {
+				return err
+			}
+			if !yield(resp, nil) {
+				return bedrockclient.ErrStreamStopped
+			}
+			return nil
+		}))
 
-		originResp, err := m.client.CreateCompletion(ctx, m.modelName, msgs, options)
+		originResp, err := m.createCompletion(ctx, msgs, options)
+		if errors.Is(err, bedrockclient.ErrStreamStopped) {
+			// The consumer stopped iterating and must not be yielded to again.
+			return
+		}
 		if err != nil {
 			yield(nil, fmt.Errorf("failed to call model: %w", err))
 			return
@@ -8,6 +8,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/bedrockruntime v1.48.0
 	github.com/google/jsonschema-go v0.3.0
 	github.com/tmc/langchaingo v0.1.14
+	golang.org/x/image v0.33.0
 	google.golang.org/adk v0.4.0
 	google.golang.org/genai v1.40.0
 )
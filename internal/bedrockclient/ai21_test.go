@@ -0,0 +1,123 @@
+package bedrockclient
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestJambaChat(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeSystem, Type: "text", Content: "You are a weather bot."},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Weather in Paris?"},
+		{Role: ChatMessageTypeAI, Type: "tool_call", ToolCallID: "call_1", ToolName: "get_weather", ToolArgs: `{"city":"Paris"}`},
+		{Role: ChatMessageTypeFunction, Type: "tool_result", ToolUseID: "call_1", Content: `{"temp":21}`},
+	}
+	options := llms.CallOptions{
+		Tools: []llms.Tool{{Type: "function", Function: &llms.FunctionDefinition{Name: "get_weather"}}},
+	}
+
+	body, err := ai21Provider{}.BuildRequest("ai21.jamba-1-5-large-v1:0", messages, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var input chatCompletionsInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		t.Fatal(err)
+	}
+	roles := []string{"system", "user", "assistant", "tool"}
+	if len(input.Messages) != len(roles) || len(input.Tools) != 1 {
+		t.Fatalf("got input %s", body)
+	}
+	for i, role := range roles {
+		if input.Messages[i].Role != role {
+			t.Errorf("message %d: got role %q, want %q", i, input.Messages[i].Role, role)
+		}
+	}
+	if input.Messages[2].ToolCalls[0].ID != "call_1" || input.Messages[3].ToolCallID != "call_1" || input.Messages[3].Content != `{"temp":21}` {
+		t.Errorf("got tool messages %s", body)
+	}
+
+	resp, err := ai21Provider{}.ParseResponse([]byte(`{
+		"id": "chat-1",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": null, "tool_calls": [
+			{"id": "call_2", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Lyon\"}"}}
+		]}, "finish_reason": "tool_calls"}],
+		"usage": {"prompt_tokens": 40, "completion_tokens": 10, "total_tokens": 50}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	choice := resp.Choices[0]
+	if len(choice.ToolCalls) != 1 || choice.ToolCalls[0].ID != "call_2" || choice.GenerationInfo["input_tokens"] != 40 {
+		t.Errorf("got choice %+v", choice)
+	}
+
+	for _, toolChoice := range []any{"required", llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: "get_weather"}}} {
+		forced := options
+		forced.ToolChoice = toolChoice
+		if _, err := (ai21Provider{}).BuildRequest("ai21.jamba-1-5-large-v1:0", messages, forced); err == nil {
+			t.Errorf("tool choice %v: want an error", toolChoice)
+		}
+	}
+	options.ToolChoice = "none"
+	if body, err := (ai21Provider{}).BuildRequest("ai21.jamba-1-5-large-v1:0", messages, options); err != nil || strings.Contains(string(body), `"tools"`) {
+		t.Errorf("tool choice none: got %s, %v", body, err)
+	}
+
+	if parser := (ai21Provider{}).NewStreamParser("ai21.j2-ultra-v1", options); parser != nil {
+		t.Error("got a stream parser for a Jurassic model")
+	}
+}
+
+func TestChatCompletionsStreamParser(t *testing.T) {
+	var text string
+	var events []StreamEvent
+	options := llms.CallOptions{
+		StreamingFunc: func(ctx context.Context, chunk []byte) error {
+			text += string(chunk)
+			return nil
+		},
+	}
+	SetMetadata(&options, MetadataStreamEventFunc, StreamEventFunc(func(ctx context.Context, event StreamEvent) error {
+		events = append(events, event)
+		return nil
+	}))
+
+	parser := ai21Provider{}.NewStreamParser("ai21.jamba-1-5-mini-v1:0", options)
+	chunks := []string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","content":"Checking"},"finish_reason":null}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]},"finish_reason":null}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]},"finish_reason":null}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":8,"total_tokens":20}}`,
+	}
+	for _, chunk := range chunks {
+		if err := parser.ParseChunk(context.Background(), []byte(chunk)); err != nil {
+			t.Fatalf("ParseChunk(%s): %v", chunk, err)
+		}
+	}
+	resp, err := parser.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	choice := resp.Choices[0]
+	if text != "Checking" || choice.StopReason != "tool_calls" || choice.GenerationInfo["output_tokens"] != 8 {
+		t.Errorf("got text %q and choice %+v", text, choice)
+	}
+	if len(choice.ToolCalls) != 1 || choice.ToolCalls[0].FunctionCall.Arguments != `{"city":"Paris"}` {
+		t.Errorf("got tool calls %+v", choice.ToolCalls)
+	}
+	want := []StreamEventType{StreamEventToolCallStart, StreamEventToolCallDelta, StreamEventToolCallDelta, StreamEventToolCallDone, StreamEventUsage}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		if event.Type != want[i] {
+			t.Errorf("event %d: got type %q, want %q", i, event.Type, want[i])
+		}
+	}
+}
@@ -3,15 +3,25 @@ package bedrockclient
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 	"github.com/tmc/langchaingo/llms"
 )
 
 // Client is a Bedrock client.
 type Client struct {
-	client *bedrockruntime.Client
+	client   *bedrockruntime.Client
+	registry *Registry
+	// pins overrides the registry for model IDs of this client only.
+	pins map[string]string
+	// objects fetches the S3 objects of models that cannot read S3.
+	objects ObjectGetter
+	// fetcher inlines image URLs, which no model reads itself.
+	fetcher *URLFetcher
 }
 
 // Message is a chunk of text or an data
@@ -22,51 +32,106 @@ type Client struct {
 type Message struct {
 	Role    ChatMessageType
 	Content string
-	// Type may be "text", "image", "tool_call", or "tool_result"
+	// Type may be "text", "image", "document", "video", "tool_call",
+	// "tool_result", "thinking" or "redacted_thinking"
 	Type string
 	// MimeType is the MIME type
 	MimeType string
+	// Name is the file name of a "document" message
+	Name string `json:"name,omitempty"`
+	// S3Location references the object of an "image", "document" or
+	// "video" message, which then has no content
+	S3Location *S3Location `json:"s3_location,omitempty"`
 	// Tool call fields
 	ToolCallID string `json:"tool_call_id,omitempty"`
 	ToolName   string `json:"tool_name,omitempty"`
 	ToolArgs   string `json:"tool_args,omitempty"`
 	// Tool result fields
 	ToolUseID string `json:"tool_use_id,omitempty"`
+	// ToolError marks a "tool_result" message as the error of a failed
+	// tool call
+	ToolError bool `json:"tool_error,omitempty"`
+	// Signature of a "thinking" message
+	Signature string `json:"signature,omitempty"`
+	// Parts are the blocks of a "tool_result" message that returns media:
+	// "text", "image", "document" and "video" messages. Content then only
+	// holds the text of the result, for models that take text results.
+	Parts []Message `json:"parts,omitempty"`
 }
 
-func getProvider(modelID string) string {
-	// Check for Nova models (including inference profiles like us.amazon.nova-*)
-	if strings.Contains(modelID, ".nova-") || strings.Contains(modelID, "amazon.nova-") {
-		return "nova"
-	}
-	parts := strings.Split(modelID, ".")
-	// For backward compatibility with the original provider detection
-	switch {
-	case strings.Contains(modelID, "ai21"):
-		return "ai21"
-	case strings.Contains(modelID, "amazon"):
-		return "amazon"
-	case strings.Contains(modelID, "anthropic"):
-		return "anthropic"
-	case strings.Contains(modelID, "cohere"):
-		return "cohere"
-	case strings.Contains(modelID, "meta"):
-		return "meta"
+// flattenMessages returns messages followed by the parts of their tool
+// results.
+func flattenMessages(messages []Message) []Message {
+	result := messages
+	for _, message := range messages {
+		if len(message.Parts) > 0 {
+			if len(result) == len(messages) {
+				result = append([]Message(nil), messages...)
+			}
+			result = append(result, message.Parts...)
+		}
 	}
+	return result
+}
 
-	// Default to using the first part of the model ID
-	if len(parts) > 0 {
-		return parts[0]
+// replaceMessages returns the messages with the ones replace returns a
+// replacement for replaced, including the parts of tool results. The
+// messages are copied rather than modified.
+func replaceMessages(messages []Message, replace func(Message) (Message, bool, error)) ([]Message, error) {
+	var result []Message
+	for i, message := range messages {
+		replaced, ok, err := replace(message)
+		if err != nil {
+			return nil, err
+		}
+		if !ok && len(message.Parts) > 0 {
+			parts, err := replaceMessages(message.Parts, replace)
+			if err != nil {
+				return nil, err
+			}
+			if &parts[0] != &message.Parts[0] {
+				replaced, ok = message, true
+				replaced.Parts = parts
+			}
+		}
+		if !ok {
+			continue
+		}
+		if result == nil {
+			result = append([]Message(nil), messages...)
+		}
+		result[i] = replaced
 	}
-
-	return ""
+	if result == nil {
+		return messages, nil
+	}
+	return result, nil
 }
 
 // NewClient creates a new Bedrock client.
 func NewClient(client *bedrockruntime.Client) *Client {
 	return &Client{
-		client: client,
+		client:   client,
+		registry: DefaultRegistry,
+		pins:     make(map[string]string),
+	}
+}
+
+// PinProvider binds a model ID or ARN to the provider registered under
+// providerName for this client only.
+func (c *Client) PinProvider(modelID, providerName string) {
+	c.pins[modelID] = providerName
+}
+
+func (c *Client) resolveProvider(modelID string) (Provider, error) {
+	if name, ok := c.pins[modelID]; ok {
+		p, ok := c.registry.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is pinned to unknown provider %q", ErrUnsupportedProvider, modelID, name)
+		}
+		return p, nil
 	}
+	return c.registry.Resolve(modelID)
 }
 
 // CreateCompletion creates a new completion response from the provider
@@ -76,23 +141,130 @@ func (c *Client) CreateCompletion(ctx context.Context,
 	messages []Message,
 	options llms.CallOptions,
 ) (*llms.ContentResponse, error) {
-	provider := getProvider(modelID)
-	switch provider {
-	case "ai21":
-		return createAi21Completion(ctx, c.client, modelID, messages, options)
-	case "amazon":
-		return createAmazonCompletion(ctx, c.client, modelID, messages, options)
-	case "nova":
-		return createNovaCompletion(ctx, c.client, modelID, messages, options)
-	case "anthropic":
-		return createAnthropicCompletion(ctx, c.client, modelID, messages, options)
-	case "cohere":
-		return createCohereCompletion(ctx, c.client, modelID, messages, options)
-	case "meta":
-		return createMetaCompletion(ctx, c.client, modelID, messages, options)
-	default:
-		return nil, errors.New("unsupported provider")
+	provider, err := c.resolveProvider(modelID)
+	if err != nil {
+		return nil, err
+	}
+	if messages, err = c.prepareMessages(ctx, provider, modelID, messages); err != nil {
+		return nil, err
+	}
+
+	var output *structuredOutput
+	if supportsStructuredOutput(provider) {
+		if output, err = getStructuredOutput(options); err != nil {
+			return nil, err
+		}
+	}
+	if output == nil {
+		return c.createCompletion(ctx, provider, modelID, messages, options)
+	}
+	resp, err := c.createCompletion(ctx, provider, modelID, messages, output.apply(options))
+	if err != nil {
+		return nil, err
+	}
+	if err := output.unwrap(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// prepareMessages checks that the model served by provider accepts the
+// media of messages and inlines the objects it cannot read itself. A nil
+// provider, for a model ID Converse may still serve, is not checked.
+func (c *Client) prepareMessages(ctx context.Context, provider Provider, modelID string, messages []Message) ([]Message, error) {
+	if provider != nil {
+		if err := checkVideoSupport(provider, modelID, messages); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if !readsS3(provider) {
+		if messages, err = c.inlineS3Objects(ctx, messages); err != nil {
+			return nil, err
+		}
+	}
+	if c.fetcher != nil {
+		if messages, err = c.fetcher.inlineImageURLs(ctx, messages); err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+func (c *Client) createCompletion(ctx context.Context, provider Provider, modelID string, messages []Message, options llms.CallOptions) (*llms.ContentResponse, error) {
+	body, err := provider.BuildRequest(modelID, messages, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.StreamingFunc != nil {
+		if parser := provider.NewStreamParser(modelID, options); parser != nil {
+			return c.invokeModelWithResponseStream(ctx, modelID, body, parser, options)
+		}
+	}
+
+	modelInput := &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(modelID),
+		Accept:      aws.String("*/*"),
+		ContentType: aws.String("application/json"),
+		Body:        body,
+	}
+	if g := getGuardrail(options); g != nil {
+		modelInput.GuardrailIdentifier = aws.String(g.Identifier)
+		modelInput.GuardrailVersion = aws.String(g.Version)
+		if g.Trace {
+			modelInput.Trace = types.TraceEnabled
+		}
+	}
+	resp, err := c.client.InvokeModel(ctx, modelInput)
+	if err != nil {
+		return nil, err
+	}
+
+	if p, ok := provider.(optionsParser); ok {
+		return p.parseResponse(resp.Body, options)
+	}
+	return provider.ParseResponse(resp.Body)
+}
+
+func (c *Client) invokeModelWithResponseStream(ctx context.Context, modelID string, body []byte, parser StreamParser, options llms.CallOptions) (*llms.ContentResponse, error) {
+	modelInput := &bedrockruntime.InvokeModelWithResponseStreamInput{
+		ModelId:     aws.String(modelID),
+		Accept:      aws.String("*/*"),
+		ContentType: aws.String("application/json"),
+		Body:        body,
+	}
+	if g := getGuardrail(options); g != nil {
+		modelInput.GuardrailIdentifier = aws.String(g.Identifier)
+		modelInput.GuardrailVersion = aws.String(g.Version)
+		if g.Trace {
+			modelInput.Trace = types.TraceEnabled
+		}
+	}
+	output, err := c.client.InvokeModelWithResponseStream(ctx, modelInput)
+	if err != nil {
+		return nil, err
+	}
+	stream := output.GetStream()
+	if stream == nil {
+		return nil, errors.New("no stream")
+	}
+	return readResponseStream(ctx, stream, parser)
+}
+
+// readResponseStream feeds the chunks of an InvokeModelWithResponseStream
+// stream to parser and returns the aggregated response.
+func readResponseStream(ctx context.Context, stream eventStream[types.ResponseStream], parser StreamParser) (*llms.ContentResponse, error) {
+	err := readStream(ctx, stream, func(e types.ResponseStream) error {
+		if v, ok := e.(*types.ResponseStreamMemberChunk); ok {
+			return parser.ParseChunk(ctx, v.Value.Bytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return parser.Result()
 }
 
 // Helper function to process input text chat
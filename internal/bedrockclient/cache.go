@@ -0,0 +1,141 @@
+package bedrockclient
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Ref: https://docs.aws.amazon.com/bedrock/latest/userguide/prompt-caching.html
+
+// MaxCachePoints is the number of cache breakpoints a request may carry.
+const MaxCachePoints = 4
+
+// GenerationInfo keys of the prompt cache token counts.
+const (
+	GenerationInfoCacheReadTokens     = "cache_read_input_tokens"
+	GenerationInfoCacheCreationTokens = "cache_creation_input_tokens"
+)
+
+// CachePolicy decides where prompt cache breakpoints are placed. Everything
+// before a breakpoint is cached, so the stable prefix of a conversation (the
+// system prompt, then the tool catalog) comes first.
+type CachePolicy struct {
+	// System places a breakpoint after the system prompt.
+	System bool
+	// Tools places a breakpoint after the last tool definition.
+	Tools bool
+	// LastMessages places a breakpoint on each of the last N messages.
+	// It is capped so that the request stays within MaxCachePoints.
+	LastMessages int
+}
+
+// DefaultCachePolicy caches the system prompt, the tools and the
+// conversation up to the latest message.
+var DefaultCachePolicy = CachePolicy{System: true, Tools: true, LastMessages: 1}
+
+func getCachePolicy(options llms.CallOptions) *CachePolicy {
+	p, _ := options.Metadata[MetadataCachePolicy].(*CachePolicy)
+	return p
+}
+
+// messageCachePoints returns how many of the last messages get a
+// breakpoint once the system and tool breakpoints are accounted for.
+func (p *CachePolicy) messageCachePoints(hasSystem, hasTools bool) int {
+	remaining := MaxCachePoints
+	if p.System && hasSystem {
+		remaining--
+	}
+	if p.Tools && hasTools {
+		remaining--
+	}
+	return max(0, min(p.LastMessages, remaining))
+}
+
+// anthropicCacheControl marks the end of a cached prompt prefix.
+type anthropicCacheControl struct {
+	// The type of the cache. Required
+	// One of: ["ephemeral"]
+	Type string `json:"type"`
+}
+
+var anthropicEphemeralCache = &anthropicCacheControl{Type: "ephemeral"}
+
+// applyAnthropicCachePoints sets cache_control on the blocks selected by
+// the cache policy of the call options.
+func applyAnthropicCachePoints(input *anthropicTextGenerationInput, options llms.CallOptions) {
+	policy := getCachePolicy(options)
+	if policy == nil {
+		return
+	}
+
+	systemPrompt, _ := input.System.(string)
+	if policy.System && systemPrompt != "" {
+		input.System = []anthropicTextGenerationInputContent{{
+			Type:         AnthropicMessageTypeText,
+			Text:         systemPrompt,
+			CacheControl: anthropicEphemeralCache,
+		}}
+	}
+	if policy.Tools && len(input.Tools) > 0 {
+		input.Tools[len(input.Tools)-1].CacheControl = anthropicEphemeralCache
+	}
+
+	n := policy.messageCachePoints(systemPrompt != "", len(input.Tools) > 0)
+	for i := len(input.Messages) - 1; i >= 0 && n > 0; i-- {
+		content := input.Messages[i].Content
+		// Reasoning blocks cannot carry a cache breakpoint.
+		for j := len(content) - 1; j >= 0; j-- {
+			if content[j].Type != AnthropicMessageTypeThinking && content[j].Type != AnthropicMessageTypeRedacted {
+				content[j].CacheControl = anthropicEphemeralCache
+				n--
+				break
+			}
+		}
+	}
+}
+
+var converseCachePoint = types.CachePointBlock{Type: types.CachePointTypeDefault}
+
+// converseCachePolicy returns the part of the cache policy of the call
+// options that the models of p take through Converse. Claude caches the
+// system prompt, the tools and the messages, Nova all but the tools, and
+// other families reject cache points.
+func converseCachePolicy(p Provider, options llms.CallOptions) *CachePolicy {
+	policy := getCachePolicy(options)
+	if policy == nil || p == nil {
+		return nil
+	}
+	switch p.Name() {
+	case "anthropic":
+		return policy
+	case "nova":
+		novaPolicy := *policy
+		novaPolicy.Tools = false
+		return &novaPolicy
+	}
+	return nil
+}
+
+// applyConverseCachePoints appends the cache point blocks selected by the
+// cache policy of the call options, as far as the models of p take them.
+func applyConverseCachePoints(p Provider, messages []types.Message, system []types.SystemContentBlock, toolConfig *types.ToolConfiguration, options llms.CallOptions) []types.SystemContentBlock {
+	policy := converseCachePolicy(p, options)
+	if policy == nil {
+		return system
+	}
+
+	hasTools := toolConfig != nil && len(toolConfig.Tools) > 0
+	if policy.System && len(system) > 0 {
+		system = append(system, &types.SystemContentBlockMemberCachePoint{Value: converseCachePoint})
+	}
+	if policy.Tools && hasTools {
+		toolConfig.Tools = append(toolConfig.Tools, &types.ToolMemberCachePoint{Value: converseCachePoint})
+	}
+
+	n := policy.messageCachePoints(len(system) > 0, hasTools)
+	for i := len(messages) - 1; i >= 0 && n > 0; i-- {
+		messages[i].Content = append(messages[i].Content, &types.ContentBlockMemberCachePoint{Value: converseCachePoint})
+		n--
+	}
+	return system
+}
@@ -0,0 +1,66 @@
+package bedrockclient
+
+import (
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Keys of llms.CallOptions.Metadata understood by the client. They carry
+// Bedrock specific settings that have no llms.CallOptions field.
+const (
+	// MetadataGuardrail holds a *GuardrailConfig.
+	MetadataGuardrail = "bedrock.guardrail"
+	// MetadataAnthropicBeta holds a []string of anthropic_beta flags.
+	MetadataAnthropicBeta = "bedrock.anthropic_beta"
+	// MetadataThinkingBudget holds the extended thinking budget in tokens
+	// as an int. Zero disables extended thinking, which models that think
+	// by default are then told explicitly.
+	MetadataThinkingBudget = "bedrock.thinking_budget"
+	// MetadataReasoningEffort holds the reasoning effort of models that
+	// take one instead of a budget, "low", "medium" or "high", as a string.
+	MetadataReasoningEffort = "bedrock.reasoning_effort"
+	// MetadataCachePolicy holds a *CachePolicy.
+	MetadataCachePolicy = "bedrock.cache_policy"
+	// MetadataStreamEventFunc holds a StreamEventFunc.
+	MetadataStreamEventFunc = "bedrock.stream_event_func"
+	// MetadataResponseSchema holds the JSON schema, as a map[string]any,
+	// the response must match. An empty schema accepts any JSON value.
+	MetadataResponseSchema = "bedrock.response_schema"
+	// MetadataCohereDocuments holds the []map[string]string documents a
+	// Cohere Command R response is grounded on, e.g. {"title": ..., "snippet": ...}.
+	// They come before the text documents of the messages.
+	MetadataCohereDocuments = "bedrock.cohere_documents"
+)
+
+// GuardrailConfig selects the Bedrock guardrail applied to a call.
+type GuardrailConfig struct {
+	// Identifier is the ID or ARN of the guardrail. Required
+	Identifier string
+	// Version is the guardrail version, e.g. "1" or "DRAFT". Required
+	Version string
+	// Trace enables the guardrail trace in the response. Optional
+	Trace bool
+	// StreamProcessingMode is "sync" or "async" and only applies to
+	// ConverseStream. Optional, default = "sync"
+	StreamProcessingMode string
+}
+
+// SetMetadata stores a Bedrock specific setting in the call options.
+func SetMetadata(options *llms.CallOptions, key string, value any) {
+	if options.Metadata == nil {
+		options.Metadata = make(map[string]interface{})
+	}
+	options.Metadata[key] = value
+}
+
+func getGuardrail(options llms.CallOptions) *GuardrailConfig {
+	g, _ := options.Metadata[MetadataGuardrail].(*GuardrailConfig)
+	if g == nil || g.Identifier == "" {
+		return nil
+	}
+	return g
+}
+
+func getAnthropicBeta(options llms.CallOptions) []string {
+	beta, _ := options.Metadata[MetadataAnthropicBeta].([]string)
+	return beta
+}
@@ -0,0 +1,548 @@
+package bedrockclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Ref: https://platform.openai.com/docs/api-reference/chat
+// Several model families on Bedrock accept the OpenAI style chat
+// completions body, with small differences handled by their providers.
+
+// Roles of a chat completions message.
+const (
+	ChatCompletionsRoleSystem    = "system"
+	ChatCompletionsRoleUser      = "user"
+	ChatCompletionsRoleAssistant = "assistant"
+	ChatCompletionsRoleTool      = "tool"
+)
+
+// chatCompletionsMessage is a single message of the conversation.
+type chatCompletionsMessage struct {
+	// One of: ["system", "user", "assistant", "tool"]
+	Role string `json:"role"`
+	// The content of the message, either a string or a list of
+	// chatCompletionsContentPart for multimodal messages.
+	Content interface{} `json:"content"`
+	// The tool calls of an assistant message. Optional
+	ToolCalls []chatCompletionsToolCall `json:"tool_calls,omitempty"`
+	// The ID of the call a tool message answers. Required for role "tool"
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	// The name of the tool a tool message answers. Optional
+	Name string `json:"name,omitempty"`
+}
+
+// chatCompletionsContentPart is a part of a multimodal message.
+type chatCompletionsContentPart struct {
+	// One of: ["text", "image_url"]
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+}
+
+// chatCompletionsToolCall is a tool call made by the model.
+type chatCompletionsToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name string `json:"name,omitempty"`
+		// The arguments as a JSON encoded string.
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+// chatCompletionsTool is the definition of a tool.
+type chatCompletionsTool struct {
+	Type     string                   `json:"type"`
+	Function *llms.FunctionDefinition `json:"function"`
+}
+
+// chatCompletionsInput is the input of a chat completions call.
+type chatCompletionsInput struct {
+	// The conversation. Required
+	Messages []chatCompletionsMessage `json:"messages"`
+	// The tools available to the model. Optional
+	Tools []chatCompletionsTool `json:"tools,omitempty"`
+	// "auto", "none", "required" or a specific function. Optional
+	ToolChoice interface{} `json:"tool_choice,omitempty"`
+	// The maximum number of tokens to generate. Optional
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// Replaces MaxTokens for reasoning models. Optional
+	MaxCompletionTokens int `json:"max_completion_tokens,omitempty"`
+	// One of: ["low", "medium", "high"]. Optional
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+	// Toggles thinking of models that take a flag. Optional
+	EnableThinking *bool `json:"enable_thinking,omitempty"`
+	// The thinking budget in tokens of models that take a flag. Optional
+	ThinkingBudget int `json:"thinking_budget,omitempty"`
+	// Toggles thinking of models that take a thinking type. Optional
+	Thinking *chatCompletionsThinking `json:"thinking,omitempty"`
+	// Use a lower value to decrease randomness in the response. Optional
+	Temperature float64 `json:"temperature,omitempty"`
+	// Use a lower value to ignore less probable options. Optional
+	TopP float64 `json:"top_p,omitempty"`
+	// Sequences that stop the generation. Optional
+	Stop []string `json:"stop,omitempty"`
+	// The number of choices to generate. Optional, default = 1
+	N int `json:"n,omitempty"`
+}
+
+// chatCompletionsThinking toggles thinking.
+type chatCompletionsThinking struct {
+	// One of: ["enabled", "disabled"]
+	Type string `json:"type"`
+}
+
+// chatCompletionsUsage is the token usage of a call.
+type chatCompletionsUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// chatCompletionsOutput is the output of a chat completions call.
+type chatCompletionsOutput struct {
+	ID      string `json:"id"`
+	Choices []struct {
+		Index   int `json:"index"`
+		Message struct {
+			Role      string                    `json:"role"`
+			Content   string                    `json:"content"`
+			ToolCalls []chatCompletionsToolCall `json:"tool_calls"`
+			// The reasoning of reasoning models, sent in either field.
+			ReasoningContent string `json:"reasoning_content"`
+			Reasoning        string `json:"reasoning"`
+		} `json:"message"`
+		// One of: ["stop", "length", "tool_calls", "content_filter"]
+		FinishReason string `json:"finish_reason"`
+		// StopReason replaces FinishReason in Mistral responses.
+		StopReason string `json:"stop_reason"`
+	} `json:"choices"`
+	Usage chatCompletionsUsage `json:"usage"`
+}
+
+// newChatCompletionsInput converts the messages and options to a chat
+// completions body. Providers adjust the fields their models do not take.
+func newChatCompletionsInput(messages []Message, options llms.CallOptions) (*chatCompletionsInput, error) {
+	inputMessages, err := processInputMessagesChatCompletions(messages)
+	if err != nil {
+		return nil, err
+	}
+	input := &chatCompletionsInput{
+		Messages:    inputMessages,
+		MaxTokens:   options.MaxTokens,
+		Temperature: options.Temperature,
+		TopP:        options.TopP,
+		Stop:        options.StopWords,
+		N:           options.CandidateCount,
+	}
+
+	if len(options.Tools) > 0 {
+		for _, tool := range options.Tools {
+			if tool.Type != "function" || tool.Function == nil {
+				return nil, fmt.Errorf("only function tools are supported, got: %s", tool.Type)
+			}
+			input.Tools = append(input.Tools, chatCompletionsTool{Type: "function", Function: tool.Function})
+		}
+		switch choice := options.ToolChoice.(type) {
+		case nil:
+		case string:
+			input.ToolChoice = choice
+		default:
+			toolChoice, err := convertToolChoiceToBedrockToolChoice(choice)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert tool choice: %w", err)
+			}
+			if toolChoice != nil && toolChoice.Type == "tool" {
+				input.ToolChoice = map[string]interface{}{
+					"type":     "function",
+					"function": map[string]string{"name": toolChoice.Name},
+				}
+			}
+		}
+	}
+	return input, nil
+}
+
+// processInputMessagesChatCompletions groups the messages by role. Tool
+// results become one "tool" message each, as they answer a single call.
+func processInputMessagesChatCompletions(messages []Message) ([]chatCompletionsMessage, error) {
+	var system string
+	var result []chatCompletionsMessage
+	// parts collects the content of the last message until it is complete.
+	var parts []chatCompletionsContentPart
+	flush := func() {
+		if len(result) == 0 {
+			return
+		}
+		last := &result[len(result)-1]
+		last.Content = chatCompletionsContent(parts)
+		parts = nil
+	}
+
+	for _, message := range messages {
+		// Reasoning blocks of other models cannot be replayed.
+		if message.Type == MessageTypeThinking || message.Type == MessageTypeRedactedThinking {
+			continue
+		}
+		role, err := getChatCompletionsRole(message.Role)
+		if err != nil {
+			return nil, err
+		}
+		if role == ChatCompletionsRoleSystem {
+			system += message.Content
+			continue
+		}
+		if n := len(result); n == 0 || result[n-1].Role != role || role == ChatCompletionsRoleTool {
+			flush()
+			result = append(result, chatCompletionsMessage{Role: role})
+		}
+		last := &result[len(result)-1]
+
+		switch message.Type {
+		case "text":
+			parts = append(parts, chatCompletionsContentPart{Type: "text", Text: message.Content})
+		case "image":
+			part := chatCompletionsContentPart{Type: "image_url", ImageURL: &struct {
+				URL string `json:"url"`
+			}{}}
+			part.ImageURL.URL = "data:" + message.MimeType + ";base64," + base64.StdEncoding.EncodeToString([]byte(message.Content))
+			parts = append(parts, part)
+		case "image_url":
+			part := chatCompletionsContentPart{Type: "image_url", ImageURL: &struct {
+				URL string `json:"url"`
+			}{URL: message.Content}}
+			parts = append(parts, part)
+		case MessageTypeDocument:
+			return nil, errors.New("documents are not supported by this model")
+		case "tool_call":
+			toolCall := chatCompletionsToolCall{ID: message.ToolCallID, Type: "function"}
+			toolCall.Function.Name = message.ToolName
+			toolCall.Function.Arguments = message.ToolArgs
+			if toolCall.Function.Arguments == "" {
+				toolCall.Function.Arguments = "{}"
+			}
+			last.ToolCalls = append(last.ToolCalls, toolCall)
+		case "tool_result":
+			last.ToolCallID = message.ToolUseID
+			parts = append(parts, chatCompletionsContentPart{Type: "text", Text: message.Content})
+		}
+	}
+	flush()
+
+	if system != "" {
+		result = append([]chatCompletionsMessage{{Role: ChatCompletionsRoleSystem, Content: system}}, result...)
+	}
+	return result, nil
+}
+
+// chatCompletionsContent returns text only content as a plain string,
+// which every model accepts, and other content as a list of parts.
+func chatCompletionsContent(parts []chatCompletionsContentPart) interface{} {
+	var text string
+	for _, part := range parts {
+		if part.Type != "text" {
+			return parts
+		}
+		text += part.Text
+	}
+	return text
+}
+
+// process the role of the message to chat completions supported role.
+func getChatCompletionsRole(role ChatMessageType) (string, error) {
+	switch role {
+	case ChatMessageTypeSystem:
+		return ChatCompletionsRoleSystem, nil
+	case ChatMessageTypeAI:
+		return ChatCompletionsRoleAssistant, nil
+	case ChatMessageTypeGeneric, ChatMessageTypeHuman:
+		return ChatCompletionsRoleUser, nil
+	case ChatMessageTypeFunction, ChatMessageTypeTool:
+		return ChatCompletionsRoleTool, nil
+	default:
+		return "", errors.New("role not supported")
+	}
+}
+
+// parseChatCompletionsResponse decodes a chat completions response body.
+func parseChatCompletionsResponse(body []byte) (*llms.ContentResponse, error) {
+	var output chatCompletionsOutput
+	if err := json.Unmarshal(body, &output); err != nil {
+		return nil, err
+	}
+	if len(output.Choices) == 0 {
+		return nil, errors.New("no results")
+	}
+
+	choices := make([]*llms.ContentChoice, len(output.Choices))
+	for i, c := range output.Choices {
+		stopReason := c.FinishReason
+		if stopReason == "" {
+			stopReason = c.StopReason
+		}
+		choice := &llms.ContentChoice{
+			Content:    c.Message.Content,
+			StopReason: stopReason,
+			GenerationInfo: map[string]interface{}{
+				"id":            output.ID,
+				"input_tokens":  output.Usage.PromptTokens,
+				"output_tokens": output.Usage.CompletionTokens,
+			},
+		}
+		if reasoning := c.Message.ReasoningContent + c.Message.Reasoning; reasoning != "" {
+			setThinkingBlocks(choice, []ThinkingBlock{{Text: reasoning}})
+		}
+		for _, toolCall := range c.Message.ToolCalls {
+			choice.ToolCalls = append(choice.ToolCalls, toolCall.toLLM())
+		}
+		if len(choice.ToolCalls) > 0 {
+			choice.FuncCall = choice.ToolCalls[0].FunctionCall
+		}
+		choices[i] = choice
+	}
+	return &llms.ContentResponse{Choices: choices}, nil
+}
+
+func (c chatCompletionsToolCall) toLLM() llms.ToolCall {
+	id := c.ID
+	if id == "" {
+		id = newToolCallID()
+	}
+	args := c.Function.Arguments
+	if args == "" {
+		args = "{}"
+	}
+	return llms.ToolCall{
+		ID:   id,
+		Type: "function",
+		FunctionCall: &llms.FunctionCall{
+			Name:      c.Function.Name,
+			Arguments: args,
+		},
+	}
+}
+
+// chatCompletionsDelta is the content a stream chunk adds to a choice.
+type chatCompletionsDelta struct {
+	Content          string `json:"content"`
+	ReasoningContent string `json:"reasoning_content"`
+	Reasoning        string `json:"reasoning"`
+	ToolCalls        []struct {
+		// Index is the position of the call in the message. Models that
+		// stream complete calls leave it out.
+		Index *int `json:"index"`
+		chatCompletionsToolCall
+	} `json:"tool_calls"`
+}
+
+func (d chatCompletionsDelta) empty() bool {
+	return d.Content == "" && d.ReasoningContent == "" && d.Reasoning == "" && len(d.ToolCalls) == 0
+}
+
+// chatCompletionsStreamChunk is a single chunk of a streamed response.
+type chatCompletionsStreamChunk struct {
+	Choices []struct {
+		Index int                  `json:"index"`
+		Delta chatCompletionsDelta `json:"delta"`
+		// Message replaces Delta in Mistral chunks.
+		Message      chatCompletionsDelta `json:"message"`
+		FinishReason *string              `json:"finish_reason"`
+		// StopReason replaces FinishReason in Mistral chunks.
+		StopReason *string `json:"stop_reason"`
+	} `json:"choices"`
+	Usage                          *chatCompletionsUsage `json:"usage"`
+	AmazonBedrockInvocationMetrics *invocationMetrics    `json:"amazon-bedrock-invocationMetrics"`
+}
+
+// chatCompletionsStreamParser accumulates the chunks of a streamed chat
+// completions response into a single choice. Only the first choice is
+// streamed.
+type chatCompletionsStreamParser struct {
+	options llms.CallOptions
+	choice  *llms.ContentChoice
+	// position of the tool calls in choice.ToolCalls by their index
+	toolIndex map[int]int
+	done      bool
+	reasoning strings.Builder
+	// splitter separates reasoning the model inlines in the content, if
+	// the model does so.
+	splitter *reasoningSplitter
+}
+
+func newChatCompletionsStreamParser(options llms.CallOptions) *chatCompletionsStreamParser {
+	return &chatCompletionsStreamParser{
+		options: options,
+		choice: &llms.ContentChoice{
+			GenerationInfo: map[string]interface{}{
+				"input_tokens":  0,
+				"output_tokens": 0,
+			},
+		},
+		toolIndex: make(map[int]int),
+	}
+}
+
+func (p *chatCompletionsStreamParser) ParseChunk(ctx context.Context, chunk []byte) error {
+	// Some models wrap their chunks in server-sent event lines.
+	chunk = bytes.TrimSpace(chunk)
+	if data, ok := bytes.CutPrefix(chunk, []byte("data:")); ok {
+		chunk = bytes.TrimSpace(data)
+	}
+	if len(chunk) == 0 || bytes.Equal(chunk, []byte("[DONE]")) {
+		return nil
+	}
+
+	var resp chatCompletionsStreamChunk
+	if err := json.Unmarshal(chunk, &resp); err != nil {
+		return err
+	}
+
+	for _, c := range resp.Choices {
+		if c.Index != 0 {
+			continue
+		}
+		delta := c.Delta
+		if delta.empty() {
+			delta = c.Message
+		}
+		reasoning, content := delta.ReasoningContent+delta.Reasoning, delta.Content
+		if p.splitter != nil {
+			inlineReasoning, answer := p.splitter.split(content)
+			reasoning, content = reasoning+inlineReasoning, answer
+		}
+		if err := p.addReasoning(ctx, reasoning); err != nil {
+			return err
+		}
+		if content != "" {
+			if err := p.options.StreamingFunc(ctx, []byte(content)); err != nil {
+				return err
+			}
+			p.choice.Content += content
+		}
+		for _, toolCall := range delta.ToolCalls {
+			index := len(p.choice.ToolCalls)
+			if toolCall.Index != nil {
+				index = *toolCall.Index
+			}
+			if err := p.parseToolCallDelta(ctx, index, toolCall.chatCompletionsToolCall); err != nil {
+				return err
+			}
+		}
+		finishReason := c.FinishReason
+		if finishReason == nil {
+			finishReason = c.StopReason
+		}
+		if finishReason != nil && *finishReason != "" {
+			p.choice.StopReason = *finishReason
+			if err := p.finishToolCalls(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch {
+	case resp.Usage != nil:
+		p.choice.GenerationInfo["input_tokens"] = resp.Usage.PromptTokens
+		p.choice.GenerationInfo["output_tokens"] = resp.Usage.CompletionTokens
+	case resp.AmazonBedrockInvocationMetrics != nil:
+		p.choice.GenerationInfo["input_tokens"] = resp.AmazonBedrockInvocationMetrics.InputTokenCount
+		p.choice.GenerationInfo["output_tokens"] = resp.AmazonBedrockInvocationMetrics.OutputTokenCount
+	default:
+		return nil
+	}
+	return emitStreamEvent(ctx, p.options, StreamEvent{Type: StreamEventUsage, Usage: copyUsage(p.choice.GenerationInfo)})
+}
+
+func (p *chatCompletionsStreamParser) addReasoning(ctx context.Context, text string) error {
+	if text == "" {
+		return nil
+	}
+	if p.options.StreamingReasoningFunc != nil {
+		if err := p.options.StreamingReasoningFunc(ctx, []byte(text), nil); err != nil {
+			return err
+		}
+	}
+	p.reasoning.WriteString(text)
+	return nil
+}
+
+func (p *chatCompletionsStreamParser) parseToolCallDelta(ctx context.Context, index int, delta chatCompletionsToolCall) error {
+	i, ok := p.toolIndex[index]
+	if !ok {
+		// The first delta of a call carries its ID and name.
+		i = len(p.choice.ToolCalls)
+		p.toolIndex[index] = i
+		id := delta.ID
+		if id == "" {
+			id = newToolCallID()
+		}
+		p.choice.ToolCalls = append(p.choice.ToolCalls, llms.ToolCall{
+			ID:           id,
+			Type:         "function",
+			FunctionCall: &llms.FunctionCall{Name: delta.Function.Name},
+		})
+		if err := emitStreamEvent(ctx, p.options, StreamEvent{Type: StreamEventToolCallStart, ToolCall: cloneToolCall(p.choice.ToolCalls[i])}); err != nil {
+			return err
+		}
+	}
+	if delta.Function.Arguments == "" {
+		return nil
+	}
+	toolCall := p.choice.ToolCalls[i]
+	toolCall.FunctionCall.Arguments += delta.Function.Arguments
+	return emitStreamEvent(ctx, p.options, StreamEvent{
+		Type:           StreamEventToolCallDelta,
+		ToolCall:       cloneToolCall(toolCall),
+		ArgumentsDelta: delta.Function.Arguments,
+	})
+}
+
+// finishToolCalls completes the tool calls once the choice has finished.
+func (p *chatCompletionsStreamParser) finishToolCalls(ctx context.Context) error {
+	if p.done {
+		return nil
+	}
+	p.done = true
+	for _, toolCall := range p.choice.ToolCalls {
+		// A tool call without arguments streams no deltas at all.
+		if toolCall.FunctionCall.Arguments == "" {
+			toolCall.FunctionCall.Arguments = "{}"
+		}
+		if err := emitStreamEvent(ctx, p.options, StreamEvent{Type: StreamEventToolCallDone, ToolCall: cloneToolCall(toolCall)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *chatCompletionsStreamParser) Result() (*llms.ContentResponse, error) {
+	if p.splitter != nil {
+		reasoning, answer := p.splitter.flush()
+		p.reasoning.WriteString(reasoning)
+		p.choice.Content += answer
+	}
+	if reasoning := strings.TrimSpace(p.reasoning.String()); reasoning != "" {
+		setThinkingBlocks(p.choice, []ThinkingBlock{{Text: reasoning}})
+	}
+	for _, toolCall := range p.choice.ToolCalls {
+		if toolCall.FunctionCall.Arguments == "" {
+			toolCall.FunctionCall.Arguments = "{}"
+		}
+	}
+	if len(p.choice.ToolCalls) > 0 {
+		p.choice.FuncCall = p.choice.ToolCalls[0].FunctionCall
+	}
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{p.choice},
+	}, nil
+}
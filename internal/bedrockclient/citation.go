@@ -0,0 +1,17 @@
+package bedrockclient
+
+// GenerationInfoCitations is the GenerationInfo key of the []Citation of a
+// grounded response.
+const GenerationInfoCitations = "citations"
+
+// Citation links a span of the response text to the documents it is
+// grounded on.
+type Citation struct {
+	// Start and End are the character offsets of the cited span.
+	Start int
+	End   int
+	// Text is the cited span.
+	Text string
+	// DocumentIDs are the IDs of the supporting documents.
+	DocumentIDs []string
+}
@@ -2,6 +2,7 @@ package bedrockclient
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -83,3 +84,168 @@ func TestClaude(t *testing.T) {
 		fmt.Println("==================")
 	}
 }
+
+func TestAnthropicThinkingRoundTrip(t *testing.T) {
+	p := anthropicProvider{}
+	options := llms.CallOptions{MaxTokens: 1000, Temperature: 0.3}
+	SetMetadata(&options, MetadataThinkingBudget, 2000)
+
+	body, err := p.BuildRequest("anthropic.claude-3-7-sonnet-20250219-v1:0", []Message{
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "weather?"},
+		{Role: ChatMessageTypeAI, Type: MessageTypeThinking, Content: "call the tool", Signature: "sig-1"},
+		{Role: ChatMessageTypeAI, Type: MessageTypeRedactedThinking, Content: "opaque"},
+		{Role: ChatMessageTypeAI, Type: "tool_call", ToolCallID: "tu_1", ToolName: "get_weather", ToolArgs: `{"city":"Hefei"}`},
+		{Role: ChatMessageTypeFunction, Type: "tool_result", ToolUseID: "tu_1", Content: `{"status":"sunny"}`},
+	}, options)
+	if err != nil {
+		t.Fatalf("BuildRequest() error = %v", err)
+	}
+
+	var input anthropicTextGenerationInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		t.Fatal(err)
+	}
+	if input.Thinking == nil || input.Thinking.BudgetTokens != 2000 {
+		t.Fatalf("thinking = %+v, want budget 2000", input.Thinking)
+	}
+	if input.MaxTokens <= 2000 || input.Temperature != 0 {
+		t.Errorf("max_tokens = %d, temperature = %v", input.MaxTokens, input.Temperature)
+	}
+	assistant := input.Messages[1].Content
+	if assistant[0].Type != "thinking" || assistant[0].Thinking != "call the tool" || assistant[0].Signature != "sig-1" {
+		t.Errorf("thinking block not replayed verbatim: %+v", assistant[0])
+	}
+	if assistant[1].Type != "redacted_thinking" || assistant[1].Data != "opaque" {
+		t.Errorf("redacted block not replayed verbatim: %+v", assistant[1])
+	}
+
+	resp, err := p.ParseResponse([]byte(`{
+		"type": "message",
+		"role": "assistant",
+		"content": [
+			{"type": "thinking", "thinking": "let me think", "signature": "sig-2"},
+			{"type": "redacted_thinking", "data": "secret"},
+			{"type": "text", "text": "Sunny."}
+		],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 10, "output_tokens": 20}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseResponse() error = %v", err)
+	}
+	blocks, _ := resp.Choices[0].GenerationInfo[GenerationInfoThinkingBlocks].([]ThinkingBlock)
+	want := []ThinkingBlock{{Text: "let me think", Signature: "sig-2"}, {RedactedData: "secret"}}
+	if len(blocks) != len(want) || blocks[0] != want[0] || blocks[1] != want[1] {
+		t.Errorf("thinking blocks = %+v, want %+v", blocks, want)
+	}
+	if resp.Choices[0].Content != "Sunny." {
+		t.Errorf("content = %q", resp.Choices[0].Content)
+	}
+}
+
+func TestAnthropicCachePoints(t *testing.T) {
+	p := anthropicProvider{}
+	options := llms.CallOptions{
+		Tools: []llms.Tool{
+			{Type: "function", Function: &llms.FunctionDefinition{Name: "a", Parameters: map[string]any{"type": "object"}}},
+			{Type: "function", Function: &llms.FunctionDefinition{Name: "b", Parameters: map[string]any{"type": "object"}}},
+		},
+	}
+	SetMetadata(&options, MetadataCachePolicy, &CachePolicy{System: true, Tools: true, LastMessages: 5})
+
+	body, err := p.BuildRequest("anthropic.claude-3-5-haiku-20241022-v1:0", []Message{
+		{Role: ChatMessageTypeSystem, Type: "text", Content: "long instructions"},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "one"},
+		{Role: ChatMessageTypeAI, Type: "text", Content: "two"},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "three"},
+	}, options)
+	if err != nil {
+		t.Fatalf("BuildRequest() error = %v", err)
+	}
+
+	var input struct {
+		System   []anthropicTextGenerationInputContent `json:"system"`
+		Tools    []BedrockTool                         `json:"tools"`
+		Messages []anthropicTextGenerationInputMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &input); err != nil {
+		t.Fatal(err)
+	}
+	if len(input.System) != 1 || input.System[0].CacheControl == nil {
+		t.Errorf("system = %+v, want one cached text block", input.System)
+	}
+	if input.Tools[0].CacheControl != nil || input.Tools[1].CacheControl == nil {
+		t.Errorf("only the last tool should carry cache_control")
+	}
+	// System and tools use two of the four breakpoints.
+	var cached int
+	for _, m := range input.Messages {
+		if m.Content[len(m.Content)-1].CacheControl != nil {
+			cached++
+		}
+	}
+	if cached != 2 || input.Messages[0].Content[0].CacheControl != nil {
+		t.Errorf("cached messages = %d, want the last 2", cached)
+	}
+
+	resp, err := p.ParseResponse([]byte(`{
+		"content": [{"type": "text", "text": "hi"}],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 5, "output_tokens": 1, "cache_read_input_tokens": 1200, "cache_creation_input_tokens": 30}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseResponse() error = %v", err)
+	}
+	info := resp.Choices[0].GenerationInfo
+	if info[GenerationInfoCacheReadTokens] != 1200 || info[GenerationInfoCacheCreationTokens] != 30 {
+		t.Errorf("cache usage = %v", info)
+	}
+}
+
+func TestAnthropicStreamEvents(t *testing.T) {
+	var events []StreamEvent
+	options := llms.CallOptions{
+		StreamingFunc: func(ctx context.Context, chunk []byte) error { return nil },
+	}
+	SetMetadata(&options, MetadataStreamEventFunc, StreamEventFunc(func(ctx context.Context, event StreamEvent) error {
+		events = append(events, event)
+		return nil
+	}))
+
+	parser := anthropicProvider{}.NewStreamParser("", options)
+	chunks := []string{
+		`{"type":"message_start","message":{"usage":{"input_tokens":12}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":7}}`,
+	}
+	for _, chunk := range chunks {
+		if err := parser.ParseChunk(context.Background(), []byte(chunk)); err != nil {
+			t.Fatalf("ParseChunk(%s): %v", chunk, err)
+		}
+	}
+
+	want := []StreamEventType{StreamEventToolCallStart, StreamEventToolCallDelta, StreamEventToolCallDelta, StreamEventToolCallDone, StreamEventUsage}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		if event.Type != want[i] {
+			t.Errorf("event %d: got type %q, want %q", i, event.Type, want[i])
+		}
+	}
+	if got := events[0].ToolCall.FunctionCall.Name; got != "get_weather" {
+		t.Errorf("start event: got name %q", got)
+	}
+	if got := events[1].ToolCall.FunctionCall.Arguments; got != `{"city":` {
+		t.Errorf("first delta was changed by later deltas: %q", got)
+	}
+	if got := events[3].ToolCall.FunctionCall.Arguments; got != `{"city":"Paris"}` {
+		t.Errorf("done event: got arguments %q", got)
+	}
+	if events[4].Usage["input_tokens"] != 12 || events[4].Usage["output_tokens"] != 7 {
+		t.Errorf("usage event: got %v", events[4].Usage)
+	}
+}
@@ -0,0 +1,146 @@
+package bedrockclient
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestCohereChat(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeSystem, Type: "text", Content: "You are a weather bot."},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Weather in Paris?"},
+		{Role: ChatMessageTypeHuman, Type: MessageTypeDocument, MimeType: "text/plain", Name: "Warnings", Content: "Storms tonight"},
+		{Role: ChatMessageTypeAI, Type: "tool_call", ToolCallID: "call_1", ToolName: "get_weather", ToolArgs: `{"city":"Paris"}`},
+		{Role: ChatMessageTypeFunction, Type: "tool_result", ToolUseID: "call_1", Content: `{"temp":21}`},
+	}
+	options := llms.CallOptions{
+		Tools: []llms.Tool{{
+			Type: "function",
+			Function: &llms.FunctionDefinition{
+				Name: "get_weather",
+				Parameters: map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"city": map[string]any{"type": "string"}, "days": map[string]any{"type": "array", "items": map[string]any{"type": "integer"}}},
+					"required":   []string{"city"},
+				},
+			},
+		}},
+	}
+	SetMetadata(&options, MetadataCohereDocuments, []map[string]string{{"title": "Forecast", "snippet": "Sunny"}})
+
+	body, err := cohereProvider{}.BuildRequest("cohere.command-r-plus-v1:0", messages, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var input cohereChatInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		t.Fatal(err)
+	}
+	if input.Preamble != "You are a weather bot." || input.Message != "" {
+		t.Errorf("got input %s", body)
+	}
+	if len(input.Documents) != 2 || input.Documents[0]["title"] != "Forecast" || input.Documents[1]["title"] != "Warnings" || input.Documents[1]["snippet"] != "Storms tonight" {
+		t.Errorf("got documents %v", input.Documents)
+	}
+	if len(input.ChatHistory) != 2 || input.ChatHistory[0].Message != "Weather in Paris?" || len(input.ChatHistory[1].ToolCalls) != 1 {
+		t.Errorf("got chat history %s", body)
+	}
+	if len(input.ToolResults) != 1 || input.ToolResults[0].Call.Name != "get_weather" || input.ToolResults[0].Outputs[0]["temp"] != 21.0 {
+		t.Errorf("got tool results %s", body)
+	}
+	params := input.Tools[0].ParameterDefinitions
+	if params["city"] != (cohereParameterDefinition{Type: "str", Required: true}) || params["days"].Type != "List[int]" {
+		t.Errorf("got parameter definitions %v", params)
+	}
+
+	for _, toolChoice := range []any{"required", llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: "get_weather"}}} {
+		forced := options
+		forced.ToolChoice = toolChoice
+		if _, err := (cohereProvider{}).BuildRequest("cohere.command-r-plus-v1:0", messages, forced); err == nil {
+			t.Errorf("tool choice %v: want an error", toolChoice)
+		}
+	}
+
+	unsupported := map[string][]Message{
+		"image":    {{Role: ChatMessageTypeHuman, Type: "image", MimeType: "image/png", Content: "png"}},
+		"pdf":      {{Role: ChatMessageTypeHuman, Type: MessageTypeDocument, MimeType: "application/pdf", Content: "%PDF"}},
+		"thinking": {{Role: ChatMessageTypeAI, Type: MessageTypeThinking, Content: "Hmm"}},
+	}
+	for name, messages := range unsupported {
+		if _, err := (cohereProvider{}).BuildRequest("cohere.command-r-plus-v1:0", messages, llms.CallOptions{}); err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+	}
+	wrongDocuments := llms.CallOptions{}
+	SetMetadata(&wrongDocuments, MetadataCohereDocuments, []string{"Sunny"})
+	if _, err := (cohereProvider{}).BuildRequest("cohere.command-r-plus-v1:0", messages, wrongDocuments); err == nil {
+		t.Error("documents of the wrong type: want an error")
+	}
+
+	resp, err := cohereProvider{}.ParseResponse([]byte(`{
+		"text": "It is sunny.",
+		"finish_reason": "COMPLETE",
+		"citations": [{"start": 6, "end": 11, "text": "sunny", "document_ids": ["doc_0"]}],
+		"tool_calls": [{"name": "get_weather", "parameters": {"city": "Lyon"}}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	choice := resp.Choices[0]
+	if choice.Content != "It is sunny." || len(choice.ToolCalls) != 1 || choice.ToolCalls[0].ID == "" {
+		t.Errorf("got choice %+v", choice)
+	}
+	if citations, _ := choice.GenerationInfo[GenerationInfoCitations].([]Citation); len(citations) != 1 || citations[0].DocumentIDs[0] != "doc_0" {
+		t.Errorf("got citations %v", choice.GenerationInfo[GenerationInfoCitations])
+	}
+}
+
+func TestCohereStream(t *testing.T) {
+	var text string
+	var events []StreamEventType
+	options := llms.CallOptions{
+		StreamingFunc: func(ctx context.Context, chunk []byte) error {
+			text += string(chunk)
+			return nil
+		},
+	}
+	SetMetadata(&options, MetadataStreamEventFunc, StreamEventFunc(func(ctx context.Context, event StreamEvent) error {
+		events = append(events, event.Type)
+		return nil
+	}))
+	parser := cohereProvider{}.NewStreamParser("cohere.command-r-plus-v1:0", options)
+	chunks := []string{
+		`{"is_finished":false,"event_type":"stream-start","generation_id":"gen_1"}`,
+		`{"is_finished":false,"event_type":"text-generation","text":"It is"}`,
+		`{"is_finished":false,"event_type":"text-generation","text":" sunny."}`,
+		`{"is_finished":false,"event_type":"citation-generation","citations":[{"start":6,"end":11,"text":"sunny","document_ids":["doc_0"]}]}`,
+		`{"is_finished":false,"event_type":"tool-calls-generation","tool_calls":[{"name":"get_weather","parameters":{"city":"Lyon"}}]}`,
+		`{"is_finished":true,"event_type":"stream-end","finish_reason":"COMPLETE","response":{"meta":{"billed_units":{"input_tokens":20,"output_tokens":9}}},"amazon-bedrock-invocationMetrics":{"inputTokenCount":20,"outputTokenCount":9}}`,
+	}
+	for _, chunk := range chunks {
+		if err := parser.ParseChunk(context.Background(), []byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := parser.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	choice := resp.Choices[0]
+	if text != "It is sunny." || choice.Content != text || choice.StopReason != "COMPLETE" || choice.GenerationInfo["output_tokens"] != 9 {
+		t.Errorf("streamed %q, got %+v", text, choice)
+	}
+	if len(choice.ToolCalls) != 1 || choice.ToolCalls[0].FunctionCall.Arguments != `{"city":"Lyon"}` {
+		t.Errorf("got tool calls %+v", choice.ToolCalls)
+	}
+	if citations, _ := choice.GenerationInfo[GenerationInfoCitations].([]Citation); len(citations) != 1 {
+		t.Errorf("got citations %v", choice.GenerationInfo[GenerationInfoCitations])
+	}
+	want := []StreamEventType{StreamEventToolCallStart, StreamEventToolCallDone, StreamEventUsage}
+	if len(events) != len(want) || events[0] != want[0] || events[1] != want[1] || events[2] != want[2] {
+		t.Errorf("got events %v, want %v", events, want)
+	}
+}
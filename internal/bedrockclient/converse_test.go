@@ -0,0 +1,277 @@
+package bedrockclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestProcessInputMessagesConverse(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeSystem, Type: "text", Content: "You are a weather bot."},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Weather here?"},
+		{Role: ChatMessageTypeHuman, Type: "image", MimeType: "image/png", Content: "png"},
+		{Role: ChatMessageTypeAI, Type: "tool_call", ToolCallID: "call_1", ToolName: "get_weather", ToolArgs: `{"city":"Paris"}`},
+		{Role: ChatMessageTypeFunction, Type: "tool_result", ToolUseID: "call_1", Content: `{"temp":21}`},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "And tomorrow?"},
+	}
+	inputMessages, system, err := processInputMessagesConverse(anthropicProvider{}, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(system) != 1 {
+		t.Fatalf("system = %v", system)
+	}
+	// The tool result and the follow-up share a user turn.
+	roles := []types.ConversationRole{types.ConversationRoleUser, types.ConversationRoleAssistant, types.ConversationRoleUser}
+	lengths := []int{2, 1, 2}
+	if len(inputMessages) != len(roles) {
+		t.Fatalf("got %d messages, want %d", len(inputMessages), len(roles))
+	}
+	for i, message := range inputMessages {
+		if message.Role != roles[i] || len(message.Content) != lengths[i] {
+			t.Errorf("message %d: role %s with %d blocks", i, message.Role, len(message.Content))
+		}
+	}
+
+	image, ok := inputMessages[0].Content[1].(*types.ContentBlockMemberImage)
+	if !ok || image.Value.Format != types.ImageFormatPng {
+		t.Errorf("got image block %#v", inputMessages[0].Content[1])
+	} else if source, ok := image.Value.Source.(*types.ImageSourceMemberBytes); !ok || string(source.Value) != "png" {
+		t.Errorf("got image source %#v", image.Value.Source)
+	}
+	toolUse, ok := inputMessages[1].Content[0].(*types.ContentBlockMemberToolUse)
+	if !ok || aws.ToString(toolUse.Value.ToolUseId) != "call_1" || aws.ToString(toolUse.Value.Name) != "get_weather" {
+		t.Errorf("got tool use block %#v", inputMessages[1].Content[0])
+	}
+	toolResult, ok := inputMessages[2].Content[0].(*types.ContentBlockMemberToolResult)
+	if !ok || aws.ToString(toolResult.Value.ToolUseId) != "call_1" || len(toolResult.Value.Content) != 1 {
+		t.Errorf("got tool result block %#v", inputMessages[2].Content[0])
+	}
+
+	if _, _, err := processInputMessagesConverse(anthropicProvider{}, []Message{{Role: ChatMessageTypeHuman, Type: "audio", Content: "wav"}}); err == nil {
+		t.Error("unknown message type: want an error")
+	}
+}
+
+func TestProcessInputMessagesConverseReasoning(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Weather in Paris?"},
+		{Role: ChatMessageTypeAI, Type: MessageTypeThinking, Content: "call the tool", Signature: "sig"},
+		{Role: ChatMessageTypeAI, Type: MessageTypeRedactedThinking, Content: "opaque"},
+		{Role: ChatMessageTypeAI, Type: "text", Content: "Let me check."},
+	}
+	tests := []struct {
+		provider Provider
+		blocks   int
+	}{
+		{anthropicProvider{}, 3},
+		// Other families reject the signatures of Claude.
+		{novaProvider{}, 1},
+		{metaProvider{}, 1},
+		{nil, 1},
+	}
+	for _, tt := range tests {
+		inputMessages, _, err := processInputMessagesConverse(tt.provider, messages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inputMessages) != 2 || len(inputMessages[1].Content) != tt.blocks {
+			t.Errorf("%v: got messages %#v", tt.provider, inputMessages)
+		}
+	}
+}
+
+func TestConverseToolConfig(t *testing.T) {
+	tools := []llms.Tool{{Type: "function", Function: &llms.FunctionDefinition{Name: "get_weather", Description: "Weather of a city"}}}
+	tests := []struct {
+		toolChoice any
+		want       types.ToolChoice
+	}{
+		{nil, nil},
+		{"none", nil},
+		{"auto", &types.ToolChoiceMemberAuto{}},
+		{"required", &types.ToolChoiceMemberAny{}},
+		{
+			llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: "get_weather"}},
+			&types.ToolChoiceMemberTool{Value: types.SpecificToolChoice{Name: aws.String("get_weather")}},
+		},
+	}
+	for _, tt := range tests {
+		config, err := getConverseToolConfig(llms.CallOptions{Tools: tools, ToolChoice: tt.toolChoice})
+		if err != nil {
+			t.Fatalf("tool choice %v: %v", tt.toolChoice, err)
+		}
+		if len(config.Tools) != 1 {
+			t.Errorf("tool choice %v: got tools %v", tt.toolChoice, config.Tools)
+		}
+		if fmt.Sprintf("%T", config.ToolChoice) != fmt.Sprintf("%T", tt.want) {
+			t.Errorf("tool choice %v: got %#v, want %T", tt.toolChoice, config.ToolChoice, tt.want)
+		}
+		if want, ok := tt.want.(*types.ToolChoiceMemberTool); ok {
+			if got, ok := config.ToolChoice.(*types.ToolChoiceMemberTool); ok && aws.ToString(got.Value.Name) != aws.ToString(want.Value.Name) {
+				t.Errorf("tool choice %v: got tool %s", tt.toolChoice, aws.ToString(got.Value.Name))
+			}
+		}
+	}
+
+	if config, err := getConverseToolConfig(llms.CallOptions{}); config != nil || err != nil {
+		t.Errorf("no tools: got %v, %v", config, err)
+	}
+	if _, err := getConverseToolConfig(llms.CallOptions{Tools: tools, ToolChoice: "sometimes"}); err == nil {
+		t.Error("unknown tool choice: want an error")
+	}
+}
+
+type httpClientFunc func(*http.Request) (*http.Response, error)
+
+func (f httpClientFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestConverseResponse(t *testing.T) {
+	var request map[string]any
+	client := bedrockruntime.New(bedrockruntime.Options{
+		Region: "us-east-1",
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		}),
+		HTTPClient: httpClientFunc(func(req *http.Request) (*http.Response, error) {
+			if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body: io.NopCloser(strings.NewReader(`{
+					"output": {"message": {"role": "assistant", "content": [
+						{"reasoningContent": {"reasoningText": {"text": "call the tool", "signature": "sig"}}},
+						{"text": "Let me check."},
+						{"toolUse": {"toolUseId": "call_1", "name": "get_weather", "input": {"city": "Paris"}}}
+					]}},
+					"stopReason": "tool_use",
+					"usage": {"inputTokens": 12, "outputTokens": 8, "totalTokens": 20},
+					"metrics": {"latencyMs": 100}
+				}`)),
+			}, nil
+		}),
+	})
+
+	options := llms.CallOptions{
+		MaxTokens: 100,
+		TopK:      40,
+		Tools:     []llms.Tool{{Type: "function", Function: &llms.FunctionDefinition{Name: "get_weather"}}},
+	}
+	messages := []Message{{Role: ChatMessageTypeHuman, Type: "text", Content: "Weather in Paris?"}}
+	resp, err := createConverseCompletion(context.Background(), client, anthropicProvider{}, "anthropic.claude-3-haiku-20240307-v1:0", messages, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields, _ := request["additionalModelRequestFields"].(map[string]any); fields["top_k"] != 40.0 {
+		t.Errorf("got request %v", request)
+	}
+
+	choice := resp.Choices[0]
+	if choice.Content != "Let me check." || choice.StopReason != "tool_use" {
+		t.Errorf("got choice %+v", choice)
+	}
+	if len(choice.ToolCalls) != 1 || choice.ToolCalls[0].ID != "call_1" || choice.FuncCall.Name != "get_weather" || choice.FuncCall.Arguments != `{"city":"Paris"}` {
+		t.Errorf("got tool calls %+v", choice.ToolCalls)
+	}
+	if choice.GenerationInfo["input_tokens"] != 12 || choice.GenerationInfo["output_tokens"] != 8 {
+		t.Errorf("got generation info %v", choice.GenerationInfo)
+	}
+	blocks, _ := choice.GenerationInfo[GenerationInfoThinkingBlocks].([]ThinkingBlock)
+	if len(blocks) != 1 || blocks[0] != (ThinkingBlock{Text: "call the tool", Signature: "sig"}) {
+		t.Errorf("got thinking blocks %+v", blocks)
+	}
+}
+
+func TestConverseInferenceConfigTopK(t *testing.T) {
+	options := llms.CallOptions{TopK: 40}
+	tests := []struct {
+		provider Provider
+		field    string
+	}{
+		{anthropicProvider{}, "top_k"},
+		{novaProvider{}, "inferenceConfig"},
+		{cohereProvider{}, "k"},
+		{metaProvider{}, ""},
+	}
+	for _, tt := range tests {
+		_, fields := getConverseInferenceConfig(tt.provider, options)
+		if _, ok := fields[tt.field]; tt.field != "" && !ok || tt.field == "" && len(fields) > 0 {
+			t.Errorf("%s: got fields %v", tt.provider.Name(), fields)
+		}
+	}
+}
+
+func TestConverseInferenceConfigThinking(t *testing.T) {
+	options := llms.CallOptions{MaxTokens: 1000, Temperature: 0.3, TopP: 0.9}
+	SetMetadata(&options, MetadataThinkingBudget, 2000)
+
+	config, fields := getConverseInferenceConfig(anthropicProvider{}, options)
+	if _, ok := fields["thinking"]; !ok {
+		t.Errorf("fields = %v, want thinking for anthropic", fields)
+	}
+	if config.Temperature != nil || config.TopP != nil || aws.ToInt32(config.MaxTokens) <= 2000 {
+		t.Errorf("config = %+v, want no sampling parameters and room for thinking", config)
+	}
+
+	// Other families reject the Anthropic thinking field.
+	for _, provider := range []Provider{novaProvider{}, metaProvider{}, nil} {
+		config, fields := getConverseInferenceConfig(provider, options)
+		if _, ok := fields["thinking"]; ok {
+			t.Errorf("%v: fields = %v, want no thinking", provider, fields)
+		}
+		if aws.ToFloat32(config.Temperature) != 0.3 || aws.ToFloat32(config.TopP) != 0.9 || aws.ToInt32(config.MaxTokens) != 1000 {
+			t.Errorf("%v: config = %+v, want the sampling parameters kept", provider, config)
+		}
+	}
+}
+
+func TestConverseCachePoints(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeSystem, Type: "text", Content: "You are a weather bot."},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Weather in Paris?"},
+	}
+	options := llms.CallOptions{Tools: []llms.Tool{{Type: "function", Function: &llms.FunctionDefinition{Name: "get_weather"}}}}
+	SetMetadata(&options, MetadataCachePolicy, &DefaultCachePolicy)
+
+	tests := []struct {
+		provider                Provider
+		system, tools, messages bool
+	}{
+		{anthropicProvider{}, true, true, true},
+		// Nova takes no tool cache points.
+		{novaProvider{}, true, false, true},
+		{metaProvider{}, false, false, false},
+		{nil, false, false, false},
+	}
+	for _, tt := range tests {
+		inputMessages, system, err := processInputMessagesConverse(tt.provider, messages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		toolConfig, err := getConverseToolConfig(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		system = applyConverseCachePoints(tt.provider, inputMessages, system, toolConfig, options)
+
+		_, systemPoint := system[len(system)-1].(*types.SystemContentBlockMemberCachePoint)
+		_, toolPoint := toolConfig.Tools[len(toolConfig.Tools)-1].(*types.ToolMemberCachePoint)
+		content := inputMessages[len(inputMessages)-1].Content
+		_, messagePoint := content[len(content)-1].(*types.ContentBlockMemberCachePoint)
+		if systemPoint != tt.system || toolPoint != tt.tools || messagePoint != tt.messages {
+			t.Errorf("%v: cache points on system %v, tools %v, messages %v", tt.provider, systemPoint, toolPoint, messagePoint)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package bedrockclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestDeepseekPrompt(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeSystem, Type: "text", Content: "Be brief."},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Hi"},
+		{Role: ChatMessageTypeAI, Type: "thinking", Content: "The user greets me."},
+		{Role: ChatMessageTypeAI, Type: "text", Content: "Hello!"},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "2+2?"},
+	}
+	prompt, err := processInputMessagesDeepseek(messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<｜begin▁of▁sentence｜>Be brief.<｜User｜>Hi<｜Assistant｜>Hello!<｜end▁of▁sentence｜><｜User｜>2+2?<｜Assistant｜><think>\n"
+	if prompt != want {
+		t.Errorf("got prompt %q, want %q", prompt, want)
+	}
+}
+
+func TestDeepseekParseResponse(t *testing.T) {
+	resp, err := deepseekProvider{}.ParseResponse([]byte(`{"choices":[{"text":"Simple addition.\n</think>\n\n4","stop_reason":"stop"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	choice := resp.Choices[0]
+	if choice.Content != "4" || choice.ReasoningContent != "Simple addition." || choice.StopReason != "stop" {
+		t.Errorf("got %+v", choice)
+	}
+	if blocks, _ := choice.GenerationInfo[GenerationInfoThinkingBlocks].([]ThinkingBlock); len(blocks) != 1 {
+		t.Errorf("got thinking blocks %v", choice.GenerationInfo)
+	}
+
+	resp, err = deepseekProvider{}.ParseResponse([]byte(`{"choices":[{"text":"Let me think","stop_reason":"length"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if choice := resp.Choices[0]; choice.Content != "" || choice.ReasoningContent != "Let me think" {
+		t.Errorf("cut off reasoning: got %+v", choice)
+	}
+}
+
+func TestDeepseekStream(t *testing.T) {
+	var text, reasoning string
+	options := llms.CallOptions{
+		StreamingFunc: func(ctx context.Context, chunk []byte) error {
+			text += string(chunk)
+			return nil
+		},
+		StreamingReasoningFunc: func(ctx context.Context, reasoningChunk, chunk []byte) error {
+			reasoning += string(reasoningChunk)
+			return nil
+		},
+	}
+	parser := deepseekProvider{}.NewStreamParser("us.deepseek.r1-v1:0", options)
+	chunks := []string{
+		`{"choices":[{"text":"Simple","stop_reason":null}]}`,
+		`{"choices":[{"text":" addition.\n</th","stop_reason":null}]}`,
+		`{"choices":[{"text":"ink>\n\n","stop_reason":null}]}`,
+		`{"choices":[{"text":"4","stop_reason":"stop"}],"amazon-bedrock-invocationMetrics":{"inputTokenCount":9,"outputTokenCount":7}}`,
+	}
+	for _, chunk := range chunks {
+		if err := parser.ParseChunk(context.Background(), []byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := parser.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "4" || reasoning != "Simple addition.\n" {
+		t.Errorf("streamed text %q, reasoning %q", text, reasoning)
+	}
+	choice := resp.Choices[0]
+	if choice.Content != "4" || choice.ReasoningContent != "Simple addition." || choice.GenerationInfo["output_tokens"] != 7 {
+		t.Errorf("got %+v", choice)
+	}
+}
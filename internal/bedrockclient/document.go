@@ -0,0 +1,142 @@
+package bedrockclient
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MessageTypeDocument is the type of a message that carries a file, such as
+// a PDF or a spreadsheet. Content holds the raw file, MimeType its type
+// and Name its file name, if known.
+const MessageTypeDocument = "document"
+
+// Document formats of the Converse API, keyed by MIME type.
+var documentFormats = map[string]string{
+	"application/pdf":    "pdf",
+	"text/csv":           "csv",
+	"application/msword": "doc",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": "docx",
+	"application/vnd.ms-excel": "xls",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": "xlsx",
+	"text/html":     "html",
+	"text/plain":    "txt",
+	"text/markdown": "md",
+}
+
+// DocumentFormat returns the document format of a MIME type, e.g. "pdf"
+// for "application/pdf", or "" if the type is not a document.
+func DocumentFormat(mimeType string) string {
+	return documentFormats[mimeType]
+}
+
+// documentLimits are the documents a model accepts in a single request.
+type documentLimits struct {
+	// formats are the accepted document formats.
+	formats []string
+	// maxCount is the maximum number of documents, zero if unlimited.
+	maxCount int
+	// maxBytes is the maximum size of a single document.
+	maxBytes int
+}
+
+var (
+	// Claude reads PDFs and plain text. Requests are limited to 32 MB.
+	anthropicDocumentLimits = documentLimits{
+		formats:  []string{"pdf", "csv", "html", "txt", "md"},
+		maxBytes: 32 << 20,
+	}
+	// Ref: https://docs.aws.amazon.com/bedrock/latest/APIReference/API_runtime_DocumentBlock.html
+	converseDocumentLimits = documentLimits{
+		formats:  []string{"pdf", "csv", "doc", "docx", "xls", "xlsx", "html", "txt", "md"},
+		maxCount: 5,
+		maxBytes: 4.5 * (1 << 20),
+	}
+)
+
+// check returns an error if the documents of messages exceed the limits.
+func (l documentLimits) check(messages []Message) error {
+	var count int
+	for _, message := range flattenMessages(messages) {
+		if message.Type != MessageTypeDocument {
+			continue
+		}
+		count++
+		format := DocumentFormat(message.MimeType)
+		supported := false
+		for _, f := range l.formats {
+			supported = supported || f == format
+		}
+		if !supported {
+			return fmt.Errorf("unsupported document type: %s", message.MimeType)
+		}
+		if len(message.Content) > l.maxBytes {
+			return fmt.Errorf("document %q is %d bytes, the model accepts at most %d", message.Name, len(message.Content), l.maxBytes)
+		}
+	}
+	if l.maxCount > 0 && count > l.maxCount {
+		return fmt.Errorf("%d documents attached, the model accepts at most %d", count, l.maxCount)
+	}
+	return nil
+}
+
+// nameDocuments returns the messages with the names of the documents
+// sanitized and made unique, as the Converse API requires. Other messages
+// are kept as they are.
+func nameDocuments(messages []Message) []Message {
+	used := make(map[string]bool)
+	// The replacement never fails.
+	messages, _ = replaceMessages(messages, func(message Message) (Message, bool, error) {
+		if message.Type != MessageTypeDocument {
+			return message, false, nil
+		}
+		base := sanitizeDocumentName(message.Name)
+		name := base
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = base + " (" + strconv.Itoa(n) + ")"
+		}
+		used[strings.ToLower(name)] = true
+		message.Name = name
+		return message, true, nil
+	})
+	return messages
+}
+
+// sanitizeDocumentName keeps the characters the Converse API accepts in
+// document names: alphanumerics, single spaces, hyphens, parentheses and
+// square brackets. The file extension is dropped, as the format is sent
+// separately.
+func sanitizeDocumentName(name string) string {
+	if i := strings.LastIndex(name, "."); i > 0 {
+		name = name[:i]
+	}
+	var sb strings.Builder
+	space := false
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-()[]", r):
+			if space && sb.Len() > 0 {
+				sb.WriteRune(' ')
+			}
+			space = false
+			sb.WriteRune(r)
+		case unicode.IsSpace(r):
+			space = true
+		default:
+			if space && sb.Len() > 0 {
+				sb.WriteRune(' ')
+			}
+			space = false
+			sb.WriteRune('-')
+		}
+	}
+	name = sb.String()
+	if name == "" {
+		return "document"
+	}
+	if runes := []rune(name); len(runes) > 200 {
+		name = string(runes[:200])
+	}
+	return name
+}
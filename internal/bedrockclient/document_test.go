@@ -0,0 +1,111 @@
+package bedrockclient
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestNameDocuments(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Compare these"},
+		{Role: ChatMessageTypeHuman, Type: MessageTypeDocument, MimeType: "application/pdf", Name: "Q1 report_final.pdf"},
+		{Role: ChatMessageTypeHuman, Type: MessageTypeDocument, MimeType: "application/pdf", Name: "Q1   report/final.pdf"},
+		{Role: ChatMessageTypeHuman, Type: MessageTypeDocument, MimeType: "text/csv"},
+	}
+	named := nameDocuments(messages)
+
+	want := []string{"", "Q1 report-final", "Q1 report-final (2)", "document"}
+	for i, message := range named {
+		if message.Name != want[i] {
+			t.Errorf("message %d: got name %q, want %q", i, message.Name, want[i])
+		}
+	}
+	if messages[1].Name != "Q1 report_final.pdf" {
+		t.Error("input messages were modified")
+	}
+}
+
+func TestDocumentLimits(t *testing.T) {
+	document := func(mimeType string, size int) Message {
+		return Message{Role: ChatMessageTypeHuman, Type: MessageTypeDocument, MimeType: mimeType, Content: strings.Repeat("a", size)}
+	}
+
+	if err := converseDocumentLimits.check([]Message{document("application/pdf", 10)}); err != nil {
+		t.Errorf("got error %v", err)
+	}
+	if err := converseDocumentLimits.check([]Message{document("application/pdf", 5<<20)}); err == nil {
+		t.Error("oversized document accepted")
+	}
+	var many []Message
+	for range 6 {
+		many = append(many, document("text/plain", 10))
+	}
+	if err := converseDocumentLimits.check(many); err == nil {
+		t.Error("too many documents accepted")
+	}
+	if err := anthropicDocumentLimits.check([]Message{document("application/vnd.ms-excel", 10)}); err == nil {
+		t.Error("spreadsheet accepted by Claude")
+	}
+}
+
+func TestDocumentRequests(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeHuman, Type: MessageTypeDocument, MimeType: "application/pdf", Name: "report.pdf", Content: "%PDF-1.7"},
+		{Role: ChatMessageTypeHuman, Type: MessageTypeDocument, MimeType: "text/markdown", Name: "notes.md", Content: "# Notes"},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Summarize"},
+	}
+
+	t.Run("anthropic", func(t *testing.T) {
+		body, err := anthropicProvider{}.BuildRequest("anthropic.claude-3-5-sonnet-20241022-v2:0", messages, llms.CallOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var input anthropicTextGenerationInput
+		if err := json.Unmarshal(body, &input); err != nil {
+			t.Fatal(err)
+		}
+		content := input.Messages[0].Content
+		if pdf := content[0]; pdf.Type != "document" || pdf.Title != "report.pdf" ||
+			pdf.Source.Type != "base64" || pdf.Source.MediaType != "application/pdf" || pdf.Source.Data != "JVBERi0xLjc=" {
+			t.Errorf("got PDF block %+v", pdf)
+		}
+		if text := content[1]; text.Type != "document" || text.Source.Type != "text" ||
+			text.Source.MediaType != "text/plain" || text.Source.Data != "# Notes" {
+			t.Errorf("got text block %+v", text)
+		}
+	})
+
+	t.Run("nova", func(t *testing.T) {
+		inputContents, _, err := processInputMessagesNova(messages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := inputContents[0].Content
+		if doc := content[0].Document; doc == nil || doc.Format != "pdf" || doc.Name != "report" || string(doc.Source.Bytes) != "%PDF-1.7" {
+			t.Errorf("got document %+v", doc)
+		}
+		if doc := content[1].Document; doc == nil || doc.Format != "md" || doc.Name != "notes" {
+			t.Errorf("got document %+v", doc)
+		}
+	})
+
+	t.Run("converse", func(t *testing.T) {
+		inputMessages, _, err := processInputMessagesConverse(anthropicProvider{}, messages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, ok := inputMessages[0].Content[0].(*types.ContentBlockMemberDocument)
+		if !ok {
+			t.Fatalf("got block %T", inputMessages[0].Content[0])
+		}
+		source, _ := block.Value.Source.(*types.DocumentSourceMemberBytes)
+		if block.Value.Format != types.DocumentFormatPdf || *block.Value.Name != "report" ||
+			source == nil || string(source.Value) != "%PDF-1.7" {
+			t.Errorf("got document %+v", block.Value)
+		}
+	})
+}
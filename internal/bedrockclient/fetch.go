@@ -0,0 +1,291 @@
+package bedrockclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// DefaultMaxFetchBytes is the size cap of a URLFetcher without MaxBytes.
+const DefaultMaxFetchBytes = 3750000
+
+// defaultFetchCacheSize is the number of images a URLFetcher caches.
+const defaultFetchCacheSize = 64
+
+// URLFetcher downloads the http(s) images of "image_url" messages and
+// inlines them, as Bedrock models do not read URLs. Fetched images are
+// cached in memory. The zero value is ready to use.
+//
+// Unless AllowPrivateNetworks is set, only public addresses are fetched
+// from: loopback, private, link-local and other internal addresses are
+// refused. The address a host resolves to is checked when connecting, so
+// that a host cannot resolve to a public address when it is checked and
+// to an internal one when it is fetched from. Proxies are not used then,
+// as they resolve hosts themselves.
+type URLFetcher struct {
+	// Client sends the requests. Optional, http.DefaultClient if nil.
+	// Unless AllowPrivateNetworks is set, its Transport must be nil or an
+	// *http.Transport.
+	Client *http.Client
+	// MaxBytes caps the size of an image. Optional, DefaultMaxFetchBytes
+	// if zero
+	MaxBytes int64
+	// AllowedHosts are the hosts images may be fetched from. An entry
+	// starting with a dot, such as ".example.com", also allows the
+	// subdomains. If empty, every host is allowed, so set it when URLs
+	// come from untrusted input.
+	AllowedHosts []string
+	// AllowPrivateNetworks allows fetching from every address, including
+	// loopback, private and link-local ones such as the instance metadata
+	// endpoint. Only set it when URLs come from trusted input.
+	AllowPrivateNetworks bool
+	// CacheSize is the number of images kept in memory. Optional, 64 if
+	// zero, negative to disable the cache
+	CacheSize int
+
+	mu    sync.Mutex
+	cache map[string]fetchedImage
+	// order holds the cached URLs, oldest first.
+	order []string
+
+	clientOnce sync.Once
+	httpClient *http.Client
+	clientErr  error
+}
+
+type fetchedImage struct {
+	mimeType string
+	data     []byte
+}
+
+// SetURLFetcher sets the fetcher image URLs are inlined with. Without one,
+// image URLs are passed to the model as they are.
+func (c *Client) SetURLFetcher(fetcher *URLFetcher) {
+	c.fetcher = fetcher
+}
+
+// inlineImageURLs returns the messages with the "image_url" messages
+// replaced by "image" messages holding the fetched images.
+func (f *URLFetcher) inlineImageURLs(ctx context.Context, messages []Message) ([]Message, error) {
+	return replaceMessages(messages, func(message Message) (Message, bool, error) {
+		if message.Type != "image_url" {
+			return message, false, nil
+		}
+		image, err := f.fetch(ctx, message.Content)
+		if err != nil {
+			return message, false, err
+		}
+		message.Type = "image"
+		message.MimeType = image.mimeType
+		message.Content = string(image.data)
+		return message, true, nil
+	})
+}
+
+func (f *URLFetcher) fetch(ctx context.Context, rawURL string) (fetchedImage, error) {
+	if image, ok := f.cached(rawURL); ok {
+		return image, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fetchedImage{}, fmt.Errorf("invalid image URL: %s", rawURL)
+	}
+	if !f.allowed(u.Hostname()) {
+		return fetchedImage{}, fmt.Errorf("image host %s is not allowed", u.Hostname())
+	}
+
+	client, err := f.client()
+	if err != nil {
+		return fetchedImage{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fetchedImage{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fetchedImage{}, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fetchedImage{}, fmt.Errorf("failed to fetch %s: %s", rawURL, resp.Status)
+	}
+
+	maxBytes := f.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxFetchBytes
+	}
+	if resp.ContentLength > maxBytes {
+		return fetchedImage{}, fmt.Errorf("image %s is larger than %d bytes", rawURL, maxBytes)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return fetchedImage{}, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	if int64(len(data)) > maxBytes {
+		return fetchedImage{}, fmt.Errorf("image %s is larger than %d bytes", rawURL, maxBytes)
+	}
+
+	mimeType := sniffImageType(data, resp.Header.Get("Content-Type"))
+	if mimeTypeToFormat(mimeType) == "" {
+		return fetchedImage{}, fmt.Errorf("unsupported image type %s at %s", mimeType, rawURL)
+	}
+	image := fetchedImage{mimeType: mimeType, data: data}
+	f.store(rawURL, image)
+	return image, nil
+}
+
+// client returns a copy of the HTTP client that refuses redirects to hosts
+// that are not allowed, before they are requested, and connections to
+// addresses that are not public unless AllowPrivateNetworks is set. The
+// copy is made once, so that its connections are reused.
+func (f *URLFetcher) client() (*http.Client, error) {
+	f.clientOnce.Do(func() {
+		client := http.DefaultClient
+		if f.Client != nil {
+			client = f.Client
+		}
+		copied := *client
+		checkRedirect := client.CheckRedirect
+		copied.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if host := req.URL.Hostname(); !f.allowed(host) {
+				return fmt.Errorf("image host %s is not allowed", host)
+			}
+			if checkRedirect != nil {
+				return checkRedirect(req, via)
+			}
+			// The default policy of http.Client.
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		}
+
+		if !f.AllowPrivateNetworks {
+			transport := http.DefaultTransport.(*http.Transport)
+			if copied.Transport != nil {
+				var ok bool
+				if transport, ok = copied.Transport.(*http.Transport); !ok {
+					f.clientErr = fmt.Errorf("URLFetcher cannot check the addresses of a %T, set AllowPrivateNetworks to use it", copied.Transport)
+					return
+				}
+			}
+			transport = transport.Clone()
+			transport.Proxy = nil
+			dialer := &net.Dialer{Control: checkPublicAddress}
+			transport.DialContext = dialer.DialContext
+			transport.DialTLSContext = nil
+			copied.Transport = transport
+		}
+		f.httpClient = &copied
+	})
+	return f.httpClient, f.clientErr
+}
+
+// internalPrefixes are the ranges beyond the loopback, private and
+// link-local ones that are not public: "this network", and the
+// carrier-grade NAT range some clouds serve their metadata endpoints from.
+var internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// checkPublicAddress is the net.Dialer.Control function that refuses to
+// connect to addresses that are not public. It sees the resolved address.
+func checkPublicAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid image address %s: %w", address, err)
+	}
+	if !isPublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("image address %s is not public", addrPort.Addr())
+	}
+	return nil
+}
+
+// isPublicAddress reports whether addr is a public unicast address.
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range internalPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// sniffImageType returns the image type of data, or the declared content
+// type if the data is not recognized. Servers often send images as
+// application/octet-stream, or with the type of another format.
+func sniffImageType(data []byte, contentType string) string {
+	if sniffed := http.DetectContentType(data); strings.HasPrefix(sniffed, "image/") {
+		return sniffed
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mediaType
+}
+
+func (f *URLFetcher) allowed(host string) bool {
+	if len(f.AllowedHosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, allowed := range f.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasPrefix(allowed, ".") && (strings.HasSuffix(host, allowed) || host == allowed[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *URLFetcher) cacheSize() int {
+	if f.CacheSize == 0 {
+		return defaultFetchCacheSize
+	}
+	return f.CacheSize
+}
+
+func (f *URLFetcher) cached(rawURL string) (fetchedImage, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	image, ok := f.cache[rawURL]
+	return image, ok
+}
+
+// store caches image, evicting the oldest images beyond the cache size.
+func (f *URLFetcher) store(rawURL string, image fetchedImage) {
+	size := f.cacheSize()
+	if size < 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cache == nil {
+		f.cache = make(map[string]fetchedImage)
+	}
+	if _, ok := f.cache[rawURL]; !ok {
+		f.order = append(f.order, rawURL)
+	}
+	f.cache[rawURL] = image
+	for len(f.order) > size {
+		delete(f.cache, f.order[0])
+		f.order = f.order[1:]
+	}
+}
@@ -0,0 +1,172 @@
+package bedrockclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough of a PNG for the type to be sniffed.
+const pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func TestURLFetcher(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/cat.png":
+			// The declared type is wrong, the sniffed one is used.
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte(pngHeader))
+		case "/large.png":
+			w.Write([]byte(pngHeader + strings.Repeat("a", 100)))
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	host, _ := url.Parse(server.URL)
+
+	fetcher := &URLFetcher{Client: server.Client(), MaxBytes: 64, AllowedHosts: []string{host.Hostname()}, AllowPrivateNetworks: true}
+	messages := []Message{
+		{Role: ChatMessageTypeHuman, Type: "image_url", Content: server.URL + "/cat.png"},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "What is this?"},
+	}
+	for range 2 {
+		inlined, err := fetcher.inlineImageURLs(context.Background(), messages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if image := inlined[0]; image.Type != "image" || image.MimeType != "image/png" || image.Content != pngHeader {
+			t.Errorf("got %+v", image)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want the second one served from the cache", requests)
+	}
+	if messages[0].Type != "image_url" {
+		t.Error("input messages were modified")
+	}
+
+	for path, want := range map[string]string{
+		"/large.png":   "larger than 64 bytes",
+		"/page.html":   "unsupported image type text/html",
+		"/missing.png": "404",
+	} {
+		_, err := fetcher.inlineImageURLs(context.Background(), []Message{{Type: "image_url", Content: server.URL + path}})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got error %v, want %q", path, err, want)
+		}
+	}
+
+	for _, rawURL := range []string{"http://example.com/cat.png", "file:///etc/passwd"} {
+		if _, err := fetcher.inlineImageURLs(context.Background(), []Message{{Type: "image_url", Content: rawURL}}); err == nil {
+			t.Errorf("%s was fetched", rawURL)
+		}
+	}
+}
+
+func TestURLFetcherRedirect(t *testing.T) {
+	var internalRequests int
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internalRequests++
+		w.Write([]byte(pngHeader))
+	}))
+	defer internal.Close()
+	allowed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 127.0.0.1 is allowed, localhost is not.
+		http.Redirect(w, r, strings.Replace(internal.URL, "127.0.0.1", "localhost", 1)+"/cat.png", http.StatusFound)
+	}))
+	defer allowed.Close()
+
+	fetcher := &URLFetcher{AllowedHosts: []string{"127.0.0.1"}, AllowPrivateNetworks: true}
+	_, err := fetcher.inlineImageURLs(context.Background(), []Message{{Type: "image_url", Content: allowed.URL + "/cat.png"}})
+	if err == nil || !strings.Contains(err.Error(), "image host localhost is not allowed") {
+		t.Errorf("got error %v for a redirect to another host", err)
+	}
+	if internalRequests != 0 {
+		t.Errorf("redirect target got %d requests", internalRequests)
+	}
+}
+
+func TestURLFetcherAllowedHosts(t *testing.T) {
+	fetcher := &URLFetcher{AllowedHosts: []string{"cdn.example.com", ".images.example.org"}}
+	for host, want := range map[string]bool{
+		"cdn.example.com":        true,
+		"CDN.example.com":        true,
+		"a.cdn.example.com":      false,
+		"images.example.org":     true,
+		"eu.images.example.org":  true,
+		"evilimages.example.org": false,
+	} {
+		if got := fetcher.allowed(host); got != want {
+			t.Errorf("allowed(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestURLFetcherPrivateNetworks(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(pngHeader))
+	}))
+	defer server.Close()
+
+	// localhost is only resolved to a loopback address when connecting.
+	for _, rawURL := range []string{server.URL + "/cat.png", strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/cat.png"} {
+		fetcher := &URLFetcher{}
+		_, err := fetcher.inlineImageURLs(context.Background(), []Message{{Type: "image_url", Content: rawURL}})
+		if err == nil || !strings.Contains(err.Error(), "is not public") {
+			t.Errorf("%s: got error %v, want the address refused", rawURL, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("server got %d requests", requests)
+	}
+
+	fetcher := &URLFetcher{Client: &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}}
+	if _, err := fetcher.inlineImageURLs(context.Background(), []Message{{Type: "image_url", Content: server.URL + "/cat.png"}}); err == nil {
+		t.Error("a transport whose addresses cannot be checked was used")
+	}
+
+	fetcher = &URLFetcher{AllowPrivateNetworks: true}
+	if _, err := fetcher.inlineImageURLs(context.Background(), []Message{{Type: "image_url", Content: server.URL + "/cat.png"}}); err != nil {
+		t.Errorf("with private networks allowed: %v", err)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestIsPublicAddress(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"::ffff:127.0.0.1": false,
+		"10.0.0.1":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"fd00:ec2::254":    false,
+		"fe80::1":          false,
+		"100.100.100.200":  false,
+		"0.0.0.0":          false,
+		"0.1.2.3":          false,
+		"224.0.0.1":        false,
+	} {
+		if got := isPublicAddress(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("isPublicAddress(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package bedrockclient
+
+// ImageLimits are the images a model accepts in a single request.
+type ImageLimits struct {
+	// MaxWidth and MaxHeight are the maximum dimensions in pixels.
+	MaxWidth  int
+	MaxHeight int
+	// MaxBytes is the maximum size of a single encoded image.
+	MaxBytes int
+	// MaxCount is the maximum number of images.
+	MaxCount int
+}
+
+// Ref: https://docs.aws.amazon.com/bedrock/latest/APIReference/API_runtime_ImageBlock.html
+// Ref: https://docs.anthropic.com/en/docs/build-with-claude/vision
+var (
+	// DefaultImageLimits are the limits of the Converse API, which also
+	// apply to models without limits of their own.
+	DefaultImageLimits = ImageLimits{MaxWidth: 8000, MaxHeight: 8000, MaxBytes: 3750000, MaxCount: 20}
+
+	// imageLimits are the limits of the providers, keyed by name.
+	imageLimits = map[string]ImageLimits{
+		// Claude takes images of up to 5 MB, but Bedrock caps them lower.
+		"anthropic": {MaxWidth: 8000, MaxHeight: 8000, MaxBytes: 3750000, MaxCount: 20},
+		// Pixtral takes up to 8 images.
+		"mistral": {MaxWidth: 8000, MaxHeight: 8000, MaxBytes: 3750000, MaxCount: 8},
+	}
+)
+
+// ImageLimits returns the image limits of the model modelID resolves to.
+func (c *Client) ImageLimits(modelID string) ImageLimits {
+	provider, err := c.resolveProvider(modelID)
+	if err != nil {
+		return DefaultImageLimits
+	}
+	if limits, ok := imageLimits[provider.Name()]; ok {
+		return limits
+	}
+	return DefaultImageLimits
+}
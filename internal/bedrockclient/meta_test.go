@@ -0,0 +1,184 @@
+package bedrockclient
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestLlamaTemplate(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeSystem, Type: "text", Content: "You are a weather bot."},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Weather in Paris?"},
+		{Role: ChatMessageTypeAI, Type: "tool_call", ToolCallID: "call_1", ToolName: "get_weather", ToolArgs: `{"city":"Paris"}`},
+		{Role: ChatMessageTypeFunction, Type: "tool_result", ToolUseID: "call_1", Content: `{"temp":21}`},
+	}
+	options := llms.CallOptions{
+		Tools: []llms.Tool{{
+			Type:     "function",
+			Function: &llms.FunctionDefinition{Name: "get_weather", Description: "Returns the weather of a city."},
+		}},
+	}
+
+	prompt, err := getLlamaTemplate("us.meta.llama3-1-70b-instruct-v1:0").render(messages, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<|begin_of_text|><|start_header_id|>system<|end_header_id|>\n\nYou are a weather bot.\n\n" + llamaToolPrompt,
+		`{"type":"function","function":{"name":"get_weather","description":"Returns the weather of a city."}}<|eot_id|>`,
+		"<|start_header_id|>user<|end_header_id|>\n\nWeather in Paris?<|eot_id|>",
+		"<|start_header_id|>assistant<|end_header_id|>\n\n" + `{"name":"get_weather","parameters":{"city":"Paris"}}<|eot_id|>`,
+		"<|start_header_id|>ipython<|end_header_id|>\n\n" + `{"temp":21}<|eot_id|>`,
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt does not contain %q:\n%s", want, prompt)
+		}
+	}
+	if !strings.HasSuffix(prompt, "<|start_header_id|>assistant<|end_header_id|>\n\n") {
+		t.Errorf("prompt does not end with the assistant header:\n%s", prompt)
+	}
+
+	prompt, err = getLlamaTemplate("meta.llama4-scout-17b-instruct-v1:0").render(messages[:2], llms.CallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<|begin_of_text|><|header_start|>system<|header_end|>\n\nYou are a weather bot.<|eot|><|header_start|>user<|header_end|>\n\nWeather in Paris?<|eot|><|header_start|>assistant<|header_end|>\n\n"; prompt != want {
+		t.Errorf("got Llama 4 prompt %q, want %q", prompt, want)
+	}
+
+	if _, err := getLlamaTemplate("meta.llama3-8b-instruct-v1:0").render(messages, options); err == nil {
+		t.Error("Llama 3.0 accepted tools")
+	}
+	for _, messageType := range []string{"image", MessageTypeDocument, MessageTypeThinking} {
+		unsupported := []Message{{Role: ChatMessageTypeHuman, Type: messageType, Content: "data"}}
+		if _, err := getLlamaTemplate("meta.llama3-1-8b-instruct-v1:0").render(unsupported, llms.CallOptions{}); err == nil {
+			t.Errorf("%s message: want an error", messageType)
+		}
+	}
+}
+
+func TestParseLlamaToolCalls(t *testing.T) {
+	tests := []struct {
+		generation string
+		names      []string
+	}{
+		{`{"name": "get_weather", "parameters": {"city": "Paris"}}`, []string{"get_weather"}},
+		{"<|python_tag|>{\"name\": \"a\", \"parameters\": {}}; {\"name\": \"b\", \"arguments\": {\"x\": 1}}", []string{"a", "b"}},
+		{`[{"name": "a", "parameters": {}}]`, []string{"a"}},
+		{`The weather in Paris is sunny.`, nil},
+		{`{"city": "Paris"}`, nil},
+		{`{"name": "a", "parameters": {}} is what I would call.`, nil},
+		// JSON answers are not calls of the offered tools.
+		{`{"name": "Alice", "age": 30}`, nil},
+	}
+	tools := map[string]bool{"get_weather": true, "a": true, "b": true}
+	for _, test := range tests {
+		toolCalls, ok := parseLlamaToolCalls(test.generation, tools)
+		if ok != (test.names != nil) || len(toolCalls) != len(test.names) {
+			t.Errorf("parseLlamaToolCalls(%q) = %v, %v", test.generation, toolCalls, ok)
+			continue
+		}
+		for i, toolCall := range toolCalls {
+			if toolCall.FunctionCall.Name != test.names[i] || !strings.HasPrefix(toolCall.ID, "call_") {
+				t.Errorf("parseLlamaToolCalls(%q): got call %+v", test.generation, toolCall)
+			}
+		}
+	}
+}
+
+func TestMetaParseResponse(t *testing.T) {
+	body := []byte(`{"generation":"{\"name\": \"Alice\", \"parameters\": {}}","stop_reason":"stop"}`)
+	tools := []llms.Tool{{Type: "function", Function: &llms.FunctionDefinition{Name: "Alice"}}}
+	tests := []struct {
+		name    string
+		options llms.CallOptions
+		call    bool
+	}{
+		{"no tools", llms.CallOptions{}, false},
+		{"tool choice none", llms.CallOptions{Tools: tools, ToolChoice: "none"}, false},
+		{"tools", llms.CallOptions{Tools: tools}, true},
+	}
+	for _, test := range tests {
+		resp, err := metaProvider{}.parseResponse(body, test.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		choice := resp.Choices[0]
+		if call := len(choice.ToolCalls) > 0; call != test.call || call == (choice.Content != "") {
+			t.Errorf("%s: got %+v", test.name, choice)
+		}
+	}
+}
+
+func TestLlamaStream(t *testing.T) {
+	tools := []llms.Tool{{Type: "function", Function: &llms.FunctionDefinition{Name: "get_weather"}}}
+	tests := []struct {
+		name    string
+		chunks  []string
+		text    string
+		toolArg string
+	}{
+		{
+			name: "text",
+			chunks: []string{
+				`{"generation":"It is","prompt_token_count":12,"generation_token_count":2,"stop_reason":null}`,
+				`{"generation":" sunny.","prompt_token_count":null,"generation_token_count":4,"stop_reason":"stop","amazon-bedrock-invocationMetrics":{"inputTokenCount":12,"outputTokenCount":4}}`,
+			},
+			text: "It is sunny.",
+		},
+		{
+			name: "tool call",
+			chunks: []string{
+				`{"generation":"{\"name\": \"get_weather\", ","prompt_token_count":12,"generation_token_count":8,"stop_reason":null}`,
+				`{"generation":"\"parameters\": {\"city\": \"Paris\"}}","prompt_token_count":null,"generation_token_count":16,"stop_reason":"stop"}`,
+			},
+			toolArg: `{"city":"Paris"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var text string
+			var events []StreamEventType
+			options := llms.CallOptions{
+				Tools: tools,
+				StreamingFunc: func(ctx context.Context, chunk []byte) error {
+					text += string(chunk)
+					return nil
+				},
+			}
+			SetMetadata(&options, MetadataStreamEventFunc, StreamEventFunc(func(ctx context.Context, event StreamEvent) error {
+				events = append(events, event.Type)
+				return nil
+			}))
+			parser := metaProvider{}.NewStreamParser("meta.llama3-1-70b-instruct-v1:0", options)
+			for _, chunk := range test.chunks {
+				if err := parser.ParseChunk(context.Background(), []byte(chunk)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			resp, err := parser.Result()
+			if err != nil {
+				t.Fatal(err)
+			}
+			choice := resp.Choices[0]
+			if text != test.text || choice.Content != test.text || choice.StopReason != "stop" {
+				t.Errorf("streamed %q, got %+v", text, choice)
+			}
+			if test.toolArg == "" {
+				if len(choice.ToolCalls) != 0 || choice.GenerationInfo["output_tokens"] != 4 {
+					t.Errorf("got %+v", choice)
+				}
+				return
+			}
+			if len(choice.ToolCalls) != 1 || choice.ToolCalls[0].FunctionCall.Arguments != test.toolArg {
+				t.Errorf("got tool calls %+v", choice.ToolCalls)
+			}
+			if len(events) != 2 || events[0] != StreamEventToolCallStart || events[1] != StreamEventToolCallDone {
+				t.Errorf("got events %v", events)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package bedrockclient
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestMistralChat(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Weather in Paris?"},
+		{Role: ChatMessageTypeHuman, Type: "image", MimeType: "image/png", Content: "\x89PNG"},
+		{Role: ChatMessageTypeAI, Type: "tool_call", ToolCallID: "toolu_01A09q90qw90lq917835lq9", ToolName: "get_weather", ToolArgs: `{"city":"Paris"}`},
+		{Role: ChatMessageTypeFunction, Type: "tool_result", ToolUseID: "toolu_01A09q90qw90lq917835lq9", Content: `{"temp":21}`},
+	}
+	options := llms.CallOptions{
+		Tools: []llms.Tool{
+			{Type: "function", Function: &llms.FunctionDefinition{Name: "get_weather"}},
+			{Type: "function", Function: &llms.FunctionDefinition{Name: "get_time"}},
+		},
+		ToolChoice: llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: "get_time"}},
+	}
+
+	body, err := mistralProvider{}.BuildRequest("us.mistral.pixtral-large-2502-v1:0", messages, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var input struct {
+		Messages []struct {
+			Role       string                    `json:"role"`
+			Content    json.RawMessage           `json:"content"`
+			ToolCalls  []chatCompletionsToolCall `json:"tool_calls"`
+			ToolCallID string                    `json:"tool_call_id"`
+			Name       string                    `json:"name"`
+		} `json:"messages"`
+		Tools      []chatCompletionsTool `json:"tools"`
+		ToolChoice string                `json:"tool_choice"`
+	}
+	if err := json.Unmarshal(body, &input); err != nil {
+		t.Fatal(err)
+	}
+	if len(input.Tools) != 1 || input.Tools[0].Function.Name != "get_time" || input.ToolChoice != "any" {
+		t.Errorf("got tools %s", body)
+	}
+	if len(input.Messages) != 3 {
+		t.Fatalf("got messages %s", body)
+	}
+	var parts []chatCompletionsContentPart
+	if err := json.Unmarshal(input.Messages[0].Content, &parts); err != nil || len(parts) != 2 || parts[1].ImageURL.URL != "data:image/png;base64,iVBORw==" {
+		t.Errorf("got user content %s", input.Messages[0].Content)
+	}
+	id := input.Messages[1].ToolCalls[0].ID
+	if !mistralToolCallIDPattern.MatchString(id) {
+		t.Errorf("got tool call ID %q", id)
+	}
+	if input.Messages[2].ToolCallID != id || input.Messages[2].Name != "get_weather" {
+		t.Errorf("tool result does not match its call: %s", body)
+	}
+	if mistralToolCallID("abcDEF123") != "abcDEF123" {
+		t.Error("valid tool call ID was changed")
+	}
+
+	// Calls and results without ID are matched in order, with the same
+	// IDs in every request.
+	messages = []Message{
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Weather in Paris and Lyon?"},
+		{Role: ChatMessageTypeAI, Type: "tool_call", ToolName: "get_weather", ToolArgs: `{"city":"Paris"}`},
+		{Role: ChatMessageTypeAI, Type: "tool_call", ToolName: "get_time", ToolArgs: `{"city":"Lyon"}`},
+		{Role: ChatMessageTypeFunction, Type: "tool_result", Content: `{"temp":21}`},
+		{Role: ChatMessageTypeFunction, Type: "tool_result", Content: `{"time":"12:00"}`},
+	}
+	var ids [][]string
+	for range 2 {
+		input, err := newMistralChatInput(messages, llms.CallOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		calls, results := input.Messages[1].ToolCalls, input.Messages[2:]
+		if len(calls) != 2 || len(results) != 2 {
+			t.Fatalf("got messages %+v", input.Messages)
+		}
+		if calls[0].ID == calls[1].ID || results[0].ToolCallID != calls[0].ID || results[1].ToolCallID != calls[1].ID || results[1].Name != "get_time" {
+			t.Errorf("results do not match their calls: %+v", input.Messages)
+		}
+		ids = append(ids, []string{calls[0].ID, calls[1].ID})
+	}
+	if ids[0][0] != ids[1][0] || ids[0][1] != ids[1][1] {
+		t.Errorf("got IDs %v, want the same IDs in every request", ids)
+	}
+}
+
+func TestMistralInstructPrompt(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeSystem, Type: "text", Content: "Be brief."},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Hi"},
+		{Role: ChatMessageTypeAI, Type: "text", Content: "Hello!"},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "How are you?"},
+	}
+	prompt, err := processInputMessagesMistral(messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<s>[INST] Be brief.\n\nHi [/INST] Hello!</s>[INST] How are you? [/INST]"; prompt != want {
+		t.Errorf("got prompt %q, want %q", prompt, want)
+	}
+
+	resp, err := mistralProvider{}.ParseResponse([]byte(`{"outputs":[{"text":"Fine.","stop_reason":"stop"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Choices[0].Content != "Fine." || resp.Choices[0].StopReason != "stop" {
+		t.Errorf("got choice %+v", resp.Choices[0])
+	}
+}
+
+func TestMistralIsChat(t *testing.T) {
+	tests := []struct {
+		modelID string
+		want    bool
+	}{
+		{"mistral.mistral-7b-instruct-v0:2", false},
+		{"mistral.mixtral-8x7b-instruct-v0:1", false},
+		{"mistral.mistral-small-2402-v1:0", false},
+		{"mistral.mistral-large-2402-v1:0", true},
+		{"us.mistral.pixtral-large-2502-v1:0", true},
+		// Later models take the chat format.
+		{"mistral.ministral-3-8b-instruct", true},
+		{"mistral.mistral-small-2503-v1:0", true},
+		{"mistral.magistral-small-2509", true},
+	}
+	for _, tt := range tests {
+		if got := (mistralProvider{}).isChat(tt.modelID); got != tt.want {
+			t.Errorf("isChat(%q) = %v, want %v", tt.modelID, got, tt.want)
+		}
+	}
+	if (mistralProvider{instruct: true}).isChat("mistral.mistral-large-2402-v1:0") {
+		t.Error("the mistral-instruct variant must take a prompt")
+	}
+}
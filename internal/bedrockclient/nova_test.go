@@ -0,0 +1,135 @@
+package bedrockclient
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestNovaStreamParser(t *testing.T) {
+	var text string
+	var events []StreamEventType
+	options := llms.CallOptions{
+		StreamingFunc: func(ctx context.Context, chunk []byte) error {
+			text += string(chunk)
+			return nil
+		},
+	}
+	SetMetadata(&options, MetadataStreamEventFunc, StreamEventFunc(func(ctx context.Context, event StreamEvent) error {
+		events = append(events, event.Type)
+		return nil
+	}))
+
+	parser := novaProvider{}.NewStreamParser("", options)
+	chunks := []string{
+		`{"messageStart":{"role":"assistant"}}`,
+		`{"contentBlockDelta":{"delta":{"text":"Let me check"},"contentBlockIndex":0}}`,
+		`{"contentBlockDelta":{"delta":{"text":" the weather."},"contentBlockIndex":0}}`,
+		`{"contentBlockStop":{"contentBlockIndex":0}}`,
+		`{"contentBlockStart":{"start":{"toolUse":{"toolUseId":"tooluse_1","name":"get_weather"}},"contentBlockIndex":1}}`,
+		`{"contentBlockDelta":{"delta":{"toolUse":{"input":"{\"city\":\"Paris\"}"}},"contentBlockIndex":1}}`,
+		`{"contentBlockStop":{"contentBlockIndex":1}}`,
+		`{"messageStop":{"stopReason":"tool_use"}}`,
+		`{"metadata":{"usage":{"inputTokens":20,"outputTokens":9}}}`,
+	}
+	for _, chunk := range chunks {
+		if err := parser.ParseChunk(context.Background(), []byte(chunk)); err != nil {
+			t.Fatalf("ParseChunk(%s): %v", chunk, err)
+		}
+	}
+
+	resp, err := parser.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	choice := resp.Choices[0]
+	if text != "Let me check the weather." || choice.Content != text {
+		t.Errorf("got streamed text %q and content %q", text, choice.Content)
+	}
+	if choice.StopReason != "tool_use" {
+		t.Errorf("got stop reason %q", choice.StopReason)
+	}
+	if len(choice.ToolCalls) != 1 || choice.ToolCalls[0].ID != "tooluse_1" ||
+		choice.ToolCalls[0].FunctionCall.Arguments != `{"city":"Paris"}` {
+		t.Errorf("got tool calls %+v", choice.ToolCalls)
+	}
+	if choice.GenerationInfo["input_tokens"] != 20 || choice.GenerationInfo["output_tokens"] != 9 {
+		t.Errorf("got usage %v", choice.GenerationInfo)
+	}
+	want := []StreamEventType{StreamEventToolCallStart, StreamEventToolCallDelta, StreamEventToolCallDone, StreamEventUsage}
+	if len(events) != len(want) {
+		t.Fatalf("got events %v, want %v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("got events %v, want %v", events, want)
+			break
+		}
+	}
+}
+
+func TestNovaToolUse(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeSystem, Type: "text", Content: "You are a weather bot."},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Weather in Paris?"},
+		{Role: ChatMessageTypeAI, Type: "tool_call", ToolCallID: "tooluse_1", ToolName: "get_weather", ToolArgs: `{"city":"Paris"}`},
+		{Role: ChatMessageTypeFunction, Type: "tool_result", ToolUseID: "tooluse_1", Content: `{"temp":21}`},
+	}
+	options := llms.CallOptions{
+		Tools: []llms.Tool{{
+			Type: "function",
+			Function: &llms.FunctionDefinition{
+				Name:        "get_weather",
+				Description: "Returns the weather of a city.",
+				Parameters:  map[string]any{"type": "object"},
+			},
+		}},
+		ToolChoice: llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: "get_weather"}},
+	}
+
+	body, err := novaProvider{}.BuildRequest("amazon.nova-pro-v1:0", messages, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var input novaTextGenerationInput
+	if err := json.Unmarshal(body, &input); err != nil {
+		t.Fatal(err)
+	}
+	if input.ToolConfig == nil || len(input.ToolConfig.Tools) != 1 || input.ToolConfig.Tools[0].ToolSpec.Name != "get_weather" {
+		t.Fatalf("got tool config %s", body)
+	}
+	if input.ToolConfig.ToolChoice == nil || input.ToolConfig.ToolChoice.Tool == nil || input.ToolConfig.ToolChoice.Tool.Name != "get_weather" {
+		t.Errorf("got tool choice %s", body)
+	}
+	if len(input.Messages) != 3 {
+		t.Fatalf("got %d messages, want user, assistant and user: %s", len(input.Messages), body)
+	}
+	if toolUse := input.Messages[1].Content[0].ToolUse; input.Messages[1].Role != NovaRoleAssistant || toolUse == nil || toolUse.ToolUseID != "tooluse_1" {
+		t.Errorf("got assistant message %s", body)
+	}
+	if toolResult := input.Messages[2].Content[0].ToolResult; input.Messages[2].Role != NovaRoleUser || toolResult == nil || toolResult.ToolUseID != "tooluse_1" {
+		t.Errorf("got tool result message %s", body)
+	}
+
+	resp, err := novaProvider{}.ParseResponse([]byte(`{
+		"output": {"message": {"role": "assistant", "content": [
+			{"text": "Checking."},
+			{"toolUse": {"toolUseId": "tooluse_2", "name": "get_weather", "input": {"city": "Lyon"}}}
+		]}},
+		"stopReason": "tool_use",
+		"usage": {"inputTokens": 30, "outputTokens": 12, "cacheReadInputTokenCount": 20, "cacheWriteInputTokenCount": 5}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	choice := resp.Choices[0]
+	if choice.Content != "Checking." || len(choice.ToolCalls) != 1 ||
+		choice.ToolCalls[0].ID != "tooluse_2" || choice.ToolCalls[0].FunctionCall.Arguments != `{"city":"Lyon"}` {
+		t.Errorf("got choice %+v", choice)
+	}
+	if choice.GenerationInfo[GenerationInfoCacheReadTokens] != 20 || choice.GenerationInfo[GenerationInfoCacheCreationTokens] != 5 {
+		t.Errorf("got generation info %v", choice.GenerationInfo)
+	}
+}
@@ -0,0 +1,125 @@
+package bedrockclient
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestOpenAICompatibleRequest(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeSystem, Type: "text", Content: "Be brief."},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "What is this?"},
+		{Role: ChatMessageTypeHuman, Type: "image", MimeType: "image/png", Content: "\x89PNG"},
+	}
+	options := llms.CallOptions{Metadata: map[string]interface{}{MetadataThinkingBudget: 0}}
+
+	tests := []struct {
+		config OpenAICompatibleConfig
+		want   map[string]interface{}
+	}{
+		{
+			config: OpenAICompatibleConfig{Name: "qwen", Thinking: ThinkingStyleEnableThinking},
+			want:   map[string]interface{}{"enable_thinking": false, "max_tokens": float64(4096)},
+		},
+		{
+			config: OpenAICompatibleConfig{Name: "kimi", Thinking: ThinkingStyleThinkingType, DefaultMaxTokens: 1000},
+			want:   map[string]interface{}{"thinking": map[string]interface{}{"type": "disabled"}, "max_tokens": float64(1000)},
+		},
+		{
+			config: OpenAICompatibleConfig{Name: "custom", ExtraBody: map[string]any{"top_k": 20}},
+			want:   map[string]interface{}{"top_k": float64(20)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.config.Name, func(t *testing.T) {
+			body, err := NewOpenAICompatibleProvider(tt.config).BuildRequest("arn:aws:bedrock:us-east-1:123456789012:imported-model/abc123", messages, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var input map[string]interface{}
+			if err := json.Unmarshal(body, &input); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.want {
+				if got, _ := json.Marshal(input[k]); string(got) != mustMarshal(t, v) {
+					t.Errorf("got %s = %s, want %s", k, got, mustMarshal(t, v))
+				}
+			}
+			if messages, _ := input["messages"].([]interface{}); len(messages) != 2 {
+				t.Errorf("got messages %s", body)
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestOpenAICompatibleRegistry(t *testing.T) {
+	r := newDefaultRegistry()
+	for modelID, name := range map[string]string{
+		"qwen.qwen3-32b-v1:0":             "qwen",
+		"moonshot.kimi-k2-thinking":       "moonshot",
+		"minimax.minimax-m2":              "minimax",
+		"us.openai.gpt-oss-120b-1:0":      "openai",
+		"us.deepseek.r1-v1:0":             "deepseek",
+		"mistral.mistral-large-2407-v1:0": "mistral",
+	} {
+		p, err := r.Resolve(modelID)
+		if err != nil || p.Name() != name {
+			t.Errorf("Resolve(%q) = %v, %v, want %s", modelID, p, err, name)
+		}
+	}
+
+	arn := "arn:aws:bedrock:us-east-1:123456789012:imported-model/abc123"
+	r.Register(NewOpenAICompatibleProvider(OpenAICompatibleConfig{Name: "my-model"}))
+	r.Pin(arn, "my-model")
+	if p, err := r.Resolve(arn); err != nil || p.Name() != "my-model" {
+		t.Errorf("Resolve(%q) = %v, %v", arn, p, err)
+	}
+}
+
+func TestOpenAICompatibleReasoningTag(t *testing.T) {
+	p := NewOpenAICompatibleProvider(OpenAICompatibleConfig{Name: "minimax", ReasoningTag: "think"})
+	resp, err := p.ParseResponse([]byte(`{"choices":[{"message":{"content":"<think>Greeting.</think>\n\nHello!"},"finish_reason":"stop"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if choice := resp.Choices[0]; choice.Content != "Hello!" || choice.ReasoningContent != "Greeting." {
+		t.Errorf("got %+v", choice)
+	}
+
+	var reasoning string
+	options := llms.CallOptions{
+		StreamingFunc: func(ctx context.Context, chunk []byte) error { return nil },
+		StreamingReasoningFunc: func(ctx context.Context, reasoningChunk, chunk []byte) error {
+			reasoning += string(reasoningChunk)
+			return nil
+		},
+	}
+	parser := p.NewStreamParser("minimax.minimax-m2", options)
+	for _, chunk := range []string{
+		`{"choices":[{"index":0,"delta":{"content":"<thi"}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"nk>Greeting.</think>Hello!"},"finish_reason":"stop"}]}`,
+	} {
+		if err := parser.ParseChunk(context.Background(), []byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err = parser.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if choice := resp.Choices[0]; choice.Content != "Hello!" || reasoning != "Greeting." {
+		t.Errorf("got content %q, reasoning %q", choice.Content, reasoning)
+	}
+}
@@ -0,0 +1,113 @@
+package bedrockclient
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestOpenAIRequest(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Weather in Paris?"},
+	}
+	options := llms.CallOptions{
+		Tools:      []llms.Tool{{Type: "function", Function: &llms.FunctionDefinition{Name: "get_weather"}}},
+		ToolChoice: "required",
+		Metadata:   map[string]interface{}{MetadataThinkingBudget: 2048},
+	}
+	body, err := NewOpenAICompatibleProvider(openaiConfig).BuildRequest("openai.gpt-oss-120b-1:0", messages, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var input map[string]interface{}
+	if err := json.Unmarshal(body, &input); err != nil {
+		t.Fatal(err)
+	}
+	if input["reasoning_effort"] != "medium" || input["tool_choice"] != "required" || input["max_completion_tokens"] != float64(4096) {
+		t.Errorf("got %s", body)
+	}
+	if _, ok := input["max_tokens"]; ok {
+		t.Errorf("max_tokens set: %s", body)
+	}
+}
+
+func TestOpenAIParseResponse(t *testing.T) {
+	body := `{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":"<reasoning>Need the weather tool.</reasoning>Let me check.","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":10,"completion_tokens":20}}`
+	resp, err := NewOpenAICompatibleProvider(openaiConfig).ParseResponse([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	choice := resp.Choices[0]
+	if choice.Content != "Let me check." || choice.ReasoningContent != "Need the weather tool." {
+		t.Errorf("got content %q, reasoning %q", choice.Content, choice.ReasoningContent)
+	}
+	if len(choice.ToolCalls) != 1 || choice.ToolCalls[0].FunctionCall.Name != "get_weather" || choice.StopReason != "tool_calls" {
+		t.Errorf("got %+v", choice)
+	}
+
+	resp, err = NewOpenAICompatibleProvider(openaiConfig).ParseResponse([]byte(`{"choices":[{"message":{"content":"4","reasoning_content":"Simple."},"finish_reason":"stop"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if choice := resp.Choices[0]; choice.Content != "4" || choice.ReasoningContent != "Simple." {
+		t.Errorf("got %+v", choice)
+	}
+}
+
+func TestOpenAIStream(t *testing.T) {
+	var text, reasoning string
+	var events []StreamEventType
+	options := llms.CallOptions{
+		StreamingFunc: func(ctx context.Context, chunk []byte) error {
+			text += string(chunk)
+			return nil
+		},
+		StreamingReasoningFunc: func(ctx context.Context, reasoningChunk, chunk []byte) error {
+			reasoning += string(reasoningChunk)
+			return nil
+		},
+		Metadata: map[string]interface{}{
+			MetadataStreamEventFunc: StreamEventFunc(func(ctx context.Context, event StreamEvent) error {
+				events = append(events, event.Type)
+				return nil
+			}),
+		},
+	}
+	parser := NewOpenAICompatibleProvider(openaiConfig).NewStreamParser("openai.gpt-oss-20b-1:0", options)
+	chunks := []string{
+		`data: {"choices":[{"index":0,"delta":{"role":"assistant","content":"<reason"}}]}`,
+		`data: {"choices":[{"index":0,"delta":{"content":"ing>Need the tool.</reasoning>"}}]}`,
+		`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":\"Paris\"}"}}]}}]}`,
+		`data: {"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":10,"completion_tokens":20}}`,
+		`data: [DONE]`,
+	}
+	for _, chunk := range chunks {
+		if err := parser.ParseChunk(context.Background(), []byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := parser.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "" || reasoning != "Need the tool." {
+		t.Errorf("streamed text %q, reasoning %q", text, reasoning)
+	}
+	choice := resp.Choices[0]
+	if choice.ReasoningContent != "Need the tool." || len(choice.ToolCalls) != 1 || choice.ToolCalls[0].FunctionCall.Arguments != `{"city":"Paris"}` {
+		t.Errorf("got %+v", choice)
+	}
+	want := []StreamEventType{StreamEventToolCallStart, StreamEventToolCallDelta, StreamEventToolCallDone, StreamEventUsage}
+	if len(events) != len(want) {
+		t.Fatalf("got events %v, want %v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("got events %v, want %v", events, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package bedrockclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ErrUnsupportedProvider is returned when no registered provider serves a model.
+var ErrUnsupportedProvider = errors.New("unsupported provider")
+
+// Provider adapts one model family to the InvokeModel API.
+//
+// A provider only translates between the internal Message list and the
+// family-specific JSON bodies; the Client performs the actual calls.
+type Provider interface {
+	// Name returns the registry key of the provider, e.g. "anthropic".
+	Name() string
+	// Match reports whether the provider serves the given base model ID,
+	// i.e. a foundation model ID with any cross-region prefix and ARN
+	// wrapper removed, such as "anthropic.claude-3-haiku-20240307-v1:0".
+	Match(baseModelID string) bool
+	// BuildRequest encodes the messages and options as an InvokeModel body.
+	// modelID is the ID the caller used, which may be an ARN.
+	BuildRequest(modelID string, messages []Message, options llms.CallOptions) ([]byte, error)
+	// ParseResponse decodes an InvokeModel response body.
+	ParseResponse(body []byte) (*llms.ContentResponse, error)
+	// NewStreamParser returns a parser for the chunks of a single
+	// InvokeModelWithResponseStream call, or nil if the model cannot
+	// stream, in which case the client falls back to InvokeModel.
+	NewStreamParser(modelID string, options llms.CallOptions) StreamParser
+}
+
+// optionsParser is implemented by providers that need the call options to
+// read a response, such as the tools that were offered.
+type optionsParser interface {
+	parseResponse(body []byte, options llms.CallOptions) (*llms.ContentResponse, error)
+}
+
+// StreamParser decodes the chunks of a streamed InvokeModel response.
+type StreamParser interface {
+	// ParseChunk consumes the payload bytes of one stream chunk and
+	// forwards any deltas to the streaming callbacks of the call options.
+	ParseChunk(ctx context.Context, chunk []byte) error
+	// Result returns the aggregated response once the stream is drained.
+	Result() (*llms.ContentResponse, error)
+}
+
+// Registry maps model IDs to providers.
+type Registry struct {
+	mu        sync.RWMutex
+	providers []Provider
+	// pins maps a model ID or ARN to a provider name.
+	pins map[string]string
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		pins: make(map[string]string),
+	}
+}
+
+// DefaultRegistry holds the built-in providers and is used by every Client.
+var DefaultRegistry = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(ai21Provider{})
+	r.Register(ai21Provider{jurassic: true})
+	r.Register(amazonProvider{})
+	r.Register(novaProvider{})
+	r.Register(anthropicProvider{})
+	r.Register(cohereProvider{})
+	r.Register(cohereProvider{command: true})
+	r.Register(metaProvider{})
+	r.Register(mistralProvider{})
+	r.Register(mistralProvider{instruct: true})
+	r.Register(deepseekProvider{})
+	r.Register(NewOpenAICompatibleProvider(openaiConfig))
+	for _, config := range openAICompatibleConfigs {
+		r.Register(NewOpenAICompatibleProvider(config))
+	}
+	return r
+}
+
+// Register adds a provider to the registry. A provider with the same name
+// is replaced, and providers registered later are matched first.
+func (r *Registry) Register(p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.providers {
+		if existing.Name() == p.Name() {
+			r.providers = append(r.providers[:i], r.providers[i+1:]...)
+			break
+		}
+	}
+	r.providers = append(r.providers, p)
+}
+
+// Pin binds a model ID or ARN to the provider with the given name,
+// bypassing model ID inference for it.
+func (r *Registry) Pin(modelID, providerName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pins[modelID] = providerName
+}
+
+// Lookup returns the provider registered under name.
+func (r *Registry) Lookup(name string) (Provider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.providers {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Resolve returns the provider serving modelID. Pinned models win over
+// inference from the model ID.
+func (r *Registry) Resolve(modelID string) (Provider, error) {
+	r.mu.RLock()
+	name, pinned := r.pins[modelID]
+	r.mu.RUnlock()
+	if pinned {
+		p, ok := r.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is pinned to unknown provider %q", ErrUnsupportedProvider, modelID, name)
+		}
+		return p, nil
+	}
+
+	baseModelID, ok := BaseModelID(modelID)
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot infer the provider of %q, pin it to a provider explicitly", ErrUnsupportedProvider, modelID)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := len(r.providers) - 1; i >= 0; i-- {
+		if r.providers[i].Match(baseModelID) {
+			return r.providers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedProvider, modelID)
+}
+
+// matchBaseModel reports whether the base model ID of modelID satisfies
+// match. Model IDs without a base model ID, such as pinned ARNs, never
+// match, so providers treat them as current models. Providers with legacy
+// formats are also registered as a variant, e.g. "mistral-instruct", to
+// pin legacy models to.
+func matchBaseModel(modelID string, match func(baseModelID string) bool) bool {
+	baseModelID, ok := BaseModelID(modelID)
+	return ok && match(baseModelID)
+}
+
+// crossRegionPrefixes are the geography prefixes of system-defined
+// cross-region inference profiles, e.g. "us." in "us.anthropic.claude-...".
+var crossRegionPrefixes = map[string]bool{
+	"us":     true,
+	"us-gov": true,
+	"eu":     true,
+	"apac":   true,
+	"au":     true,
+	"ca":     true,
+	"jp":     true,
+	"global": true,
+}
+
+// BaseModelID strips the ARN wrapper and cross-region prefix from a model
+// ID, returning the foundation model ID it refers to. It reports false for
+// identifiers that do not name a foundation model, such as application
+// inference profile, provisioned throughput and custom or imported model ARNs.
+func BaseModelID(modelID string) (string, bool) {
+	id := modelID
+	if strings.HasPrefix(id, "arn:") {
+		// arn:partition:bedrock:region:account:resource-type/resource-id
+		parts := strings.SplitN(id, ":", 6)
+		if len(parts) != 6 {
+			return "", false
+		}
+		resourceType, resourceID, ok := strings.Cut(parts[5], "/")
+		if !ok {
+			return "", false
+		}
+		switch resourceType {
+		case "foundation-model", "inference-profile":
+			id = resourceID
+		default:
+			return "", false
+		}
+	}
+
+	if prefix, rest, ok := strings.Cut(id, "."); ok && crossRegionPrefixes[prefix] {
+		id = rest
+	}
+	if !strings.Contains(id, ".") {
+		return "", false
+	}
+	return id, true
+}
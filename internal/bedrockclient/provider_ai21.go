@@ -1,11 +1,9 @@
 package bedrockclient
 
 import (
-	"context"
 	"encoding/json"
+	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/tmc/langchaingo/llms"
 )
 
@@ -75,7 +73,53 @@ const (
 	Ai21CompletionReasonEndOfText = "endoftext"
 )
 
-func createAi21Completion(ctx context.Context, client *bedrockruntime.Client, modelID string, messages []Message, options llms.CallOptions) (*llms.ContentResponse, error) {
+// Ref: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-jamba.html
+// Jamba models take a chat completions body, see chat_completions.go.
+
+// ai21Provider serves the AI21 Labs models. The Jurassic-2 models are only
+// kept as a legacy fallback.
+type ai21Provider struct {
+	// jurassic selects the Jurassic-2 format for every model. The
+	// "ai21-jurassic" variant is only used by pinned models.
+	jurassic bool
+}
+
+func (p ai21Provider) Name() string {
+	if p.jurassic {
+		return "ai21-jurassic"
+	}
+	return "ai21"
+}
+
+func (p ai21Provider) Match(baseModelID string) bool {
+	return !p.jurassic && strings.HasPrefix(baseModelID, "ai21.")
+}
+
+// isJamba reports whether modelID is a Jamba model.
+func (p ai21Provider) isJamba(modelID string) bool {
+	return !p.jurassic && !matchBaseModel(modelID, func(baseModelID string) bool {
+		return !strings.HasPrefix(baseModelID, "ai21.jamba")
+	})
+}
+
+func (p ai21Provider) BuildRequest(modelID string, messages []Message, options llms.CallOptions) ([]byte, error) {
+	if p.isJamba(modelID) {
+		if options.ToolChoice == "none" {
+			options.Tools = nil
+		}
+		if err := checkToolChoiceAuto("Jamba", options.ToolChoice); err != nil {
+			return nil, err
+		}
+		input, err := newChatCompletionsInput(messages, options)
+		if err != nil {
+			return nil, err
+		}
+		// Jamba always picks tools on its own.
+		input.ToolChoice = nil
+		input.MaxTokens = getMaxTokens(options.MaxTokens, 4096)
+		return json.Marshal(input)
+	}
+
 	txt := processInputMessagesGeneric(messages)
 	inputContent := ai21TextGenerationInput{
 		Prompt:        txt,
@@ -95,28 +139,19 @@ func createAi21Completion(ctx context.Context, client *bedrockruntime.Client, mo
 		NumResults: options.CandidateCount,
 	}
 
-	body, err := json.Marshal(inputContent)
-	if err != nil {
-		return nil, err
-	}
-
-	modelInput := bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(modelID),
-		Body:        body,
-		Accept:      aws.String("*/*"),
-		ContentType: aws.String("application/json"),
-	}
-
-	resp, err := client.InvokeModel(ctx, &modelInput)
-	if err != nil {
-		return nil, err
-	}
+	return json.Marshal(inputContent)
+}
 
+func (ai21Provider) ParseResponse(body []byte) (*llms.ContentResponse, error) {
 	var output ai21TextGenerationOutput
-	err = json.Unmarshal(resp.Body, &output)
+	err := json.Unmarshal(body, &output)
 	if err != nil {
 		return nil, err
 	}
+	// Only Jurassic responses have completions.
+	if output.Completions == nil {
+		return parseChatCompletionsResponse(body)
+	}
 
 	choices := make([]*llms.ContentChoice, len(output.Completions))
 	for i, completion := range output.Completions {
@@ -133,3 +168,11 @@ func createAi21Completion(ctx context.Context, client *bedrockruntime.Client, mo
 
 	return &llms.ContentResponse{Choices: choices}, nil
 }
+
+func (p ai21Provider) NewStreamParser(modelID string, options llms.CallOptions) StreamParser {
+	// Jurassic models cannot stream.
+	if !p.isJamba(modelID) {
+		return nil
+	}
+	return newChatCompletionsStreamParser(options)
+}
@@ -4,9 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/tmc/langchaingo/llms"
 )
 
@@ -56,12 +55,17 @@ const (
 	AmazonCompletionReasonContentFiltered = "CONTENT_FILTERED"
 )
 
-func createAmazonCompletion(ctx context.Context,
-	client *bedrockruntime.Client,
-	modelID string,
-	messages []Message,
-	options llms.CallOptions,
-) (*llms.ContentResponse, error) {
+// amazonProvider serves the Amazon Titan text models.
+type amazonProvider struct{}
+
+func (amazonProvider) Name() string { return "amazon" }
+
+func (amazonProvider) Match(baseModelID string) bool {
+	return strings.HasPrefix(baseModelID, "amazon.titan-text") ||
+		strings.HasPrefix(baseModelID, "amazon.titan-tg1")
+}
+
+func (amazonProvider) BuildRequest(modelID string, messages []Message, options llms.CallOptions) ([]byte, error) {
 	txt := processInputMessagesGeneric(messages)
 
 	inputContent := amazonTextGenerationInput{
@@ -74,24 +78,12 @@ func createAmazonCompletion(ctx context.Context,
 		},
 	}
 
-	body, err := json.Marshal(inputContent)
-	if err != nil {
-		return nil, err
-	}
-
-	modelInput := &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(modelID),
-		Accept:      aws.String("*/*"),
-		ContentType: aws.String("application/json"),
-		Body:        body,
-	}
-	resp, err := client.InvokeModel(ctx, modelInput)
-	if err != nil {
-		return nil, err
-	}
+	return json.Marshal(inputContent)
+}
 
+func (amazonProvider) ParseResponse(body []byte) (*llms.ContentResponse, error) {
 	var output amazonTextGenerationOutput
-	err = json.Unmarshal(resp.Body, &output)
+	err := json.Unmarshal(body, &output)
 	if err != nil {
 		return nil, err
 	}
@@ -117,3 +109,68 @@ func createAmazonCompletion(ctx context.Context,
 		Choices: contentChoices,
 	}, nil
 }
+
+func (amazonProvider) NewStreamParser(modelID string, options llms.CallOptions) StreamParser {
+	return &amazonStreamParser{
+		options: options,
+		choice: &llms.ContentChoice{
+			GenerationInfo: map[string]any{
+				"input_tokens":  0,
+				"output_tokens": 0,
+			},
+		},
+	}
+}
+
+// amazonTextGenerationChunk is a single chunk of a streamed response.
+type amazonTextGenerationChunk struct {
+	// The generated text
+	OutputText string `json:"outputText"`
+	// The number of tokens in the prompt, only set on the first chunk
+	InputTextTokenCount *int `json:"inputTextTokenCount"`
+	// The number of tokens generated so far
+	TotalOutputTextTokenCount int `json:"totalOutputTextTokenCount"`
+	// The reason for the completion of the generation, only set on the last chunk
+	CompletionReason *string `json:"completionReason"`
+
+	AmazonBedrockInvocationMetrics *invocationMetrics `json:"amazon-bedrock-invocationMetrics"`
+}
+
+// amazonStreamParser accumulates the chunks of a streamed response into a
+// single choice.
+type amazonStreamParser struct {
+	options llms.CallOptions
+	choice  *llms.ContentChoice
+}
+
+func (p *amazonStreamParser) ParseChunk(ctx context.Context, chunk []byte) error {
+	var resp amazonTextGenerationChunk
+	if err := json.Unmarshal(chunk, &resp); err != nil {
+		return err
+	}
+	if resp.OutputText != "" {
+		if err := p.options.StreamingFunc(ctx, []byte(resp.OutputText)); err != nil {
+			return err
+		}
+		p.choice.Content += resp.OutputText
+	}
+	if resp.InputTextTokenCount != nil {
+		p.choice.GenerationInfo["input_tokens"] = *resp.InputTextTokenCount
+	}
+	if resp.TotalOutputTextTokenCount > 0 {
+		p.choice.GenerationInfo["output_tokens"] = resp.TotalOutputTextTokenCount
+	}
+	if resp.CompletionReason != nil {
+		p.choice.StopReason = *resp.CompletionReason
+	}
+	if metrics := resp.AmazonBedrockInvocationMetrics; metrics != nil {
+		return metrics.reportUsage(ctx, p.options, p.choice)
+	}
+	return nil
+}
+
+func (p *amazonStreamParser) Result() (*llms.ContentResponse, error) {
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{p.choice},
+	}, nil
+}
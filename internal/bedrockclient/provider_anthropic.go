@@ -7,10 +7,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 	"github.com/tmc/langchaingo/llms"
 )
 
@@ -20,10 +18,11 @@ import (
 // anthropicBinGenerationInputSource is the source of the content.
 type anthropicBinGenerationInputSource struct {
 	// The type of the source. Required
-	// One of: "base64,url"
+	// One of: "base64", "url", "text"
 	Type string `json:"type"`
 	// The MIME type of the source. Required
-	// One of: []"image/jpeg", "image/png", "image/gif", "image/bmp", "image/webp"]
+	// One of: ["image/jpeg", "image/png", "image/gif", "image/bmp", "image/webp",
+	// "application/pdf", "text/plain"]
 	MediaType string `json:"media_type,omitempty"`
 	// The data of the source. Required
 	// For example if type is "base64" then data is a base64 encoded string
@@ -35,19 +34,29 @@ type anthropicBinGenerationInputSource struct {
 // anthropicTextGenerationInputContent is a single message in the input.
 type anthropicTextGenerationInputContent struct {
 	// The type of the content. Required.
-	// One of: "text", "image", "tool_result", "tool_use"
+	// One of: "text", "image", "document", "tool_result", "tool_use", "thinking", "redacted_thinking"
 	Type string `json:"type"`
-	// The source of the content. Required if type is "image"
+	// The source of the content. Required if type is "image" or "document"
 	Source *anthropicBinGenerationInputSource `json:"source,omitempty"`
+	// The title of a document. Optional
+	Title string `json:"title,omitempty"`
 	// The text content. Required if type is "text"
 	Text string `json:"text,omitempty"`
-	// Tool result fields
-	ToolUseID string `json:"tool_use_id,omitempty"`
-	Content   string `json:"content,omitempty"`
+	// Tool result fields. Content is a string, or the blocks of a result
+	// that returns media
+	ToolUseID string      `json:"tool_use_id,omitempty"`
+	Content   interface{} `json:"content,omitempty"`
+	IsError   bool        `json:"is_error,omitempty"`
 	// Tool use fields (for tool calls from AI)
 	ID    string      `json:"id,omitempty"`
 	Name  string      `json:"name,omitempty"`
 	Input interface{} `json:"input,omitempty"`
+	// Thinking fields, replayed verbatim from a previous response
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+	// CacheControl marks the end of a cached prompt prefix. Optional
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
 }
 
 type anthropicTextGenerationInputMessage struct {
@@ -66,7 +75,8 @@ type anthropicTextGenerationInput struct {
 	// The maximum number of tokens to generate per result. Required
 	MaxTokens int `json:"max_tokens"`
 	// The system prompt to use. Optional
-	System string `json:"system,omitempty"`
+	// Either a string, or a list of text blocks when it carries a cache breakpoint.
+	System interface{} `json:"system,omitempty"`
 	// The messages to use. Required
 	Messages []*anthropicTextGenerationInputMessage `json:"messages"`
 	// The amount of randomness injected into the response. Optional, default = 1
@@ -83,6 +93,20 @@ type anthropicTextGenerationInput struct {
 	Tools []BedrockTool `json:"tools,omitempty"`
 	// Tool choice configuration. Optional
 	ToolChoice *BedrockToolChoice `json:"tool_choice,omitempty"`
+	// Beta features to enable, e.g. "token-efficient-tools-2025-02-19". Optional
+	AnthropicBeta []string `json:"anthropic_beta,omitempty"`
+	// Extended thinking configuration. Optional
+	Thinking *anthropicThinkingConfig `json:"thinking,omitempty"`
+}
+
+// anthropicThinkingConfig enables extended thinking.
+type anthropicThinkingConfig struct {
+	// The type of the configuration. Required
+	// One of: ["enabled", "disabled"]
+	Type string `json:"type"`
+	// The number of tokens the model may spend on thinking.
+	// Must be at least 1024 and less than max_tokens. Required if type is "enabled"
+	BudgetTokens int `json:"budget_tokens,omitempty"`
 }
 
 // anthropicTextGenerationOutput is the generated output.
@@ -100,21 +124,32 @@ type anthropicTextGenerationOutput struct {
 	// One of: ["end_turn", "max_tokens", "stop_sequence", "tool_use"]
 	StopReason string `json:"stop_reason"`
 	// Which custom stop sequence was matched, if any.
-	StopSequence string `json:"stop_sequence"`
-	Usage        struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
-	} `json:"usage"`
+	StopSequence string         `json:"stop_sequence"`
+	Usage        anthropicUsage `json:"usage"`
+}
+
+// anthropicUsage is the token usage of a response.
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	// Tokens read from and written to the prompt cache. They are not
+	// included in InputTokens.
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
 }
 
 // anthropicContentBlock represents a content block in Anthropic response
 type anthropicContentBlock struct {
-	Type string `json:"type"` // "text" or "tool_use"
+	Type string `json:"type"` // "text", "tool_use", "thinking" or "redacted_thinking"
 	Text string `json:"text,omitempty"`
 	// Tool use fields
 	ID    string      `json:"id,omitempty"`
 	Name  string      `json:"name,omitempty"`
 	Input interface{} `json:"input,omitempty"`
+	// Thinking fields
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
 }
 
 // Finish reason for the completion of the generation.
@@ -140,16 +175,27 @@ const (
 const (
 	AnthropicMessageTypeText       = "text"
 	AnthropicMessageTypeImage      = "image"
+	AnthropicMessageTypeDocument   = "document"
 	AnthropicMessageTypeToolUse    = "tool_use"
 	AnthropicMessageTypeToolResult = "tool_result"
+	AnthropicMessageTypeThinking   = "thinking"
+	AnthropicMessageTypeRedacted   = "redacted_thinking"
 )
 
-func createAnthropicCompletion(ctx context.Context,
-	client *bedrockruntime.Client,
-	modelID string,
-	messages []Message,
-	options llms.CallOptions,
-) (*llms.ContentResponse, error) {
+// anthropicProvider serves the Anthropic Claude models through the
+// Messages API.
+type anthropicProvider struct{}
+
+func (anthropicProvider) Name() string { return "anthropic" }
+
+func (anthropicProvider) Match(baseModelID string) bool {
+	return strings.HasPrefix(baseModelID, "anthropic.")
+}
+
+func (anthropicProvider) BuildRequest(modelID string, messages []Message, options llms.CallOptions) ([]byte, error) {
+	if err := anthropicDocumentLimits.check(messages); err != nil {
+		return nil, err
+	}
 	inputContents, systemPrompt, err := processInputMessagesAnthropic(messages)
 	if err != nil {
 		return nil, err
@@ -158,12 +204,29 @@ func createAnthropicCompletion(ctx context.Context,
 	input := anthropicTextGenerationInput{
 		AnthropicVersion: AnthropicLatestVersion,
 		MaxTokens:        getMaxTokens(options.MaxTokens, 2048),
-		System:           systemPrompt,
 		Messages:         inputContents,
 		Temperature:      options.Temperature,
 		TopP:             options.TopP,
 		TopK:             options.TopK,
 		StopSequences:    options.StopWords,
+		AnthropicBeta:    getAnthropicBeta(options),
+	}
+	if systemPrompt != "" {
+		input.System = systemPrompt
+	}
+
+	// Extended thinking is incompatible with temperature and top_k and
+	// requires top_p >= 0.95, so the sampling parameters are dropped.
+	if budget := getThinkingBudget(options); budget > 0 {
+		input.Thinking = &anthropicThinkingConfig{Type: "enabled", BudgetTokens: budget}
+		if input.MaxTokens <= budget {
+			input.MaxTokens += budget
+		}
+		input.Temperature = 0
+		input.TopK = 0
+		if input.TopP < 0.95 {
+			input.TopP = 0
+		}
 	}
 
 	// Add tools if provided
@@ -184,34 +247,14 @@ func createAnthropicCompletion(ctx context.Context,
 		}
 	}
 
-	body, err := json.Marshal(input)
-	if err != nil {
-		return nil, err
-	}
-
-	if options.StreamingFunc != nil {
-		modelInput := &bedrockruntime.InvokeModelWithResponseStreamInput{
-			ModelId:     aws.String(modelID),
-			Accept:      aws.String("*/*"),
-			ContentType: aws.String("application/json"),
-			Body:        body,
-		}
-		return parseStreamingCompletionResponse(ctx, client, modelInput, options)
-	}
+	applyAnthropicCachePoints(&input, options)
 
-	modelInput := &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(modelID),
-		Accept:      aws.String("*/*"),
-		ContentType: aws.String("application/json"),
-		Body:        body,
-	}
-	resp, err := client.InvokeModel(ctx, modelInput)
-	if err != nil {
-		return nil, err
-	}
+	return json.Marshal(input)
+}
 
+func (anthropicProvider) ParseResponse(body []byte) (*llms.ContentResponse, error) {
 	var output anthropicTextGenerationOutput
-	err = json.Unmarshal(resp.Body, &output)
+	err := json.Unmarshal(body, &output)
 	if err != nil {
 		return nil, err
 	}
@@ -226,16 +269,23 @@ func createAnthropicCompletion(ctx context.Context,
 	choice := &llms.ContentChoice{
 		StopReason: output.StopReason,
 		GenerationInfo: map[string]interface{}{
-			"input_tokens":  output.Usage.InputTokens,
-			"output_tokens": output.Usage.OutputTokens,
+			"input_tokens":                    output.Usage.InputTokens,
+			"output_tokens":                   output.Usage.OutputTokens,
+			GenerationInfoCacheReadTokens:     output.Usage.CacheReadInputTokens,
+			GenerationInfoCacheCreationTokens: output.Usage.CacheCreationInputTokens,
 		},
 	}
 
 	var textContent string
 	var toolCalls []llms.ToolCall
+	var thinkingBlocks []ThinkingBlock
 
 	for _, block := range output.Content {
 		switch block.Type {
+		case AnthropicMessageTypeThinking:
+			thinkingBlocks = append(thinkingBlocks, ThinkingBlock{Text: block.Thinking, Signature: block.Signature})
+		case AnthropicMessageTypeRedacted:
+			thinkingBlocks = append(thinkingBlocks, ThinkingBlock{RedactedData: block.Data})
 		case "text":
 			textContent += block.Text
 		case "tool_use":
@@ -254,6 +304,7 @@ func createAnthropicCompletion(ctx context.Context,
 
 	choice.Content = textContent
 	choice.ToolCalls = toolCalls
+	setThinkingBlocks(choice, thinkingBlocks)
 
 	// Set legacy FuncCall field for backward compatibility
 	if len(toolCalls) > 0 {
@@ -265,6 +316,18 @@ func createAnthropicCompletion(ctx context.Context,
 	}, nil
 }
 
+func (anthropicProvider) NewStreamParser(modelID string, options llms.CallOptions) StreamParser {
+	return &anthropicStreamParser{
+		options: options,
+		choice: &llms.ContentChoice{
+			GenerationInfo: map[string]interface{}{},
+		},
+		contentType:   make(map[int]string),
+		thinkingIndex: make(map[int]int),
+		toolIndex:     make(map[int]int),
+	}
+}
+
 type streamingCompletionResponseChunk struct {
 	Type  string `json:"type"`
 	Index int    `json:"index"`
@@ -274,6 +337,8 @@ type streamingCompletionResponseChunk struct {
 		StopReason   string `json:"stop_reason"`
 		StopSequence any    `json:"stop_sequence"`
 		PartialJson  string `json:"partial_json"`
+		Thinking     string `json:"thinking"`
+		Signature    string `json:"signature"`
 	} `json:"delta"`
 	AmazonBedrockInvocationMetrics struct {
 		InputTokenCount   int `json:"inputTokenCount"`
@@ -285,17 +350,14 @@ type streamingCompletionResponseChunk struct {
 		OutputTokens int `json:"output_tokens"`
 	} `json:"usage"`
 	Message struct {
-		ID           string `json:"id"`
-		Type         string `json:"type"`
-		Role         string `json:"role"`
-		Content      []any  `json:"content"`
-		Model        string `json:"model"`
-		StopReason   any    `json:"stop_reason"`
-		StopSequence any    `json:"stop_sequence"`
-		Usage        struct {
-			InputTokens  int `json:"input_tokens"`
-			OutputTokens int `json:"output_tokens"`
-		} `json:"usage"`
+		ID           string         `json:"id"`
+		Type         string         `json:"type"`
+		Role         string         `json:"role"`
+		Content      []any          `json:"content"`
+		Model        string         `json:"model"`
+		StopReason   any            `json:"stop_reason"`
+		StopSequence any            `json:"stop_sequence"`
+		Usage        anthropicUsage `json:"usage"`
 	} `json:"message"`
 	ContentBlock struct {
 		Type  string `json:"type"`
@@ -303,74 +365,121 @@ type streamingCompletionResponseChunk struct {
 		Name  string `json:"name"`
 		Input struct {
 		} `json:"input"`
+		Data string `json:"data"`
 	} `json:"content_block"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicStreamParser accumulates the events of a streamed Messages API
+// response into a single choice.
+type anthropicStreamParser struct {
+	options     llms.CallOptions
+	choice      *llms.ContentChoice
+	contentType map[int]string
+	// thinking blocks in output order, and their position by block index
+	thinking      []ThinkingBlock
+	thinkingIndex map[int]int
+	// position of the tool calls in choice.ToolCalls by block index
+	toolIndex map[int]int
 }
 
-func parseStreamingCompletionResponse(ctx context.Context, client *bedrockruntime.Client, modelInput *bedrockruntime.InvokeModelWithResponseStreamInput, options llms.CallOptions) (*llms.ContentResponse, error) {
-	output, err := client.InvokeModelWithResponseStream(ctx, modelInput)
+func (p *anthropicStreamParser) ParseChunk(ctx context.Context, chunk []byte) error {
+	var resp streamingCompletionResponseChunk
+	err := json.NewDecoder(bytes.NewReader(chunk)).Decode(&resp)
 	if err != nil {
-		return nil, err
-	}
-	stream := output.GetStream()
-	if stream == nil {
-		return nil, errors.New("no stream")
+		return err
 	}
-	defer stream.Close()
-
-	contentchoices := []*llms.ContentChoice{{GenerationInfo: map[string]interface{}{}}}
-
-	ContentType := make(map[int]string)
-	for e := range stream.Events() {
-		if err = stream.Err(); err != nil {
-			return nil, err
-		}
 
-		if v, ok := e.(*types.ResponseStreamMemberChunk); ok {
-			var resp streamingCompletionResponseChunk
-			err := json.NewDecoder(bytes.NewReader(v.Value.Bytes)).Decode(&resp)
-			if err != nil {
-				return nil, err
+	switch resp.Type {
+	case "error":
+		return &StreamError{Type: resp.Error.Type, Message: resp.Error.Message}
+	case "message_start":
+		p.choice.GenerationInfo["input_tokens"] = resp.Message.Usage.InputTokens
+		p.choice.GenerationInfo[GenerationInfoCacheReadTokens] = resp.Message.Usage.CacheReadInputTokens
+		p.choice.GenerationInfo[GenerationInfoCacheCreationTokens] = resp.Message.Usage.CacheCreationInputTokens
+	case "content_block_start":
+		p.contentType[resp.Index] = resp.ContentBlock.Type
+		switch resp.ContentBlock.Type {
+		case AnthropicMessageTypeThinking:
+			p.thinkingIndex[resp.Index] = len(p.thinking)
+			p.thinking = append(p.thinking, ThinkingBlock{})
+		case AnthropicMessageTypeRedacted:
+			p.thinkingIndex[resp.Index] = len(p.thinking)
+			p.thinking = append(p.thinking, ThinkingBlock{RedactedData: resp.ContentBlock.Data})
+		case "tool_use":
+			p.toolIndex[resp.Index] = len(p.choice.ToolCalls)
+			toolCall := llms.ToolCall{
+				ID:   resp.ContentBlock.ID,
+				Type: "function",
+				FunctionCall: &llms.FunctionCall{
+					Name: resp.ContentBlock.Name,
+				},
 			}
-
-			switch resp.Type {
-			case "message_start":
-				contentchoices[0].GenerationInfo["input_tokens"] = resp.Message.Usage.InputTokens
-			case "content_block_start":
-				ContentType[resp.Index] = resp.ContentBlock.Type
-				if resp.ContentBlock.Type == "tool_use" {
-					contentchoices[0].ToolCalls = append(contentchoices[0].ToolCalls, llms.ToolCall{
-						ID:   resp.ContentBlock.ID,
-						Type: "function",
-						FunctionCall: &llms.FunctionCall{
-							Name: resp.ContentBlock.Name,
-						},
-					})
-				}
-			case "content_block_delta":
-				switch ContentType[resp.Index] {
-				case "tool_use":
-					contentchoices[0].ToolCalls[len(contentchoices[0].ToolCalls)-1].FunctionCall.Arguments += resp.Delta.PartialJson
-				case "text":
-					if err = options.StreamingFunc(ctx, []byte(resp.Delta.Text)); err != nil {
-						if err.Error() != "yield break" {
-							return nil, err
-						}
-						return nil, nil
+			p.choice.ToolCalls = append(p.choice.ToolCalls, toolCall)
+			return emitStreamEvent(ctx, p.options, StreamEvent{
+				Type:     StreamEventToolCallStart,
+				ToolCall: cloneToolCall(toolCall),
+			})
+		}
+	case "content_block_delta":
+		switch p.contentType[resp.Index] {
+		case AnthropicMessageTypeThinking:
+			block := &p.thinking[p.thinkingIndex[resp.Index]]
+			switch resp.Delta.Type {
+			case "thinking_delta":
+				if p.options.StreamingReasoningFunc != nil {
+					if err = p.options.StreamingReasoningFunc(ctx, []byte(resp.Delta.Thinking), nil); err != nil {
+						return err
 					}
-					contentchoices[0].Content += resp.Delta.Text
 				}
-			case "message_delta":
-				contentchoices[0].StopReason = resp.Delta.StopReason
-				contentchoices[0].GenerationInfo["output_tokens"] = resp.Usage.OutputTokens
+				block.Text += resp.Delta.Thinking
+			case "signature_delta":
+				block.Signature += resp.Delta.Signature
+			}
+		case "tool_use":
+			toolCall := p.choice.ToolCalls[p.toolIndex[resp.Index]]
+			toolCall.FunctionCall.Arguments += resp.Delta.PartialJson
+			return emitStreamEvent(ctx, p.options, StreamEvent{
+				Type:           StreamEventToolCallDelta,
+				ToolCall:       cloneToolCall(toolCall),
+				ArgumentsDelta: resp.Delta.PartialJson,
+			})
+		case "text":
+			if err = p.options.StreamingFunc(ctx, []byte(resp.Delta.Text)); err != nil {
+				return err
 			}
+			p.choice.Content += resp.Delta.Text
 		}
+	case "content_block_stop":
+		if p.contentType[resp.Index] == "tool_use" {
+			toolCall := p.choice.ToolCalls[p.toolIndex[resp.Index]]
+			// A tool call without arguments streams no deltas at all.
+			if toolCall.FunctionCall.Arguments == "" {
+				toolCall.FunctionCall.Arguments = "{}"
+			}
+			return emitStreamEvent(ctx, p.options, StreamEvent{
+				Type:     StreamEventToolCallDone,
+				ToolCall: cloneToolCall(toolCall),
+			})
+		}
+	case "message_delta":
+		p.choice.StopReason = resp.Delta.StopReason
+		p.choice.GenerationInfo["output_tokens"] = resp.Usage.OutputTokens
+		return emitStreamEvent(ctx, p.options, StreamEvent{
+			Type:  StreamEventUsage,
+			Usage: copyUsage(p.choice.GenerationInfo),
+		})
 	}
-	if err = stream.Err(); err != nil {
-		return nil, err
-	}
+	return nil
+}
 
+func (p *anthropicStreamParser) Result() (*llms.ContentResponse, error) {
+	setThinkingBlocks(p.choice, p.thinking)
 	return &llms.ContentResponse{
-		Choices: contentchoices,
+		Choices: []*llms.ContentChoice{p.choice},
 	}, nil
 }
 
@@ -469,6 +578,25 @@ func getAnthropicInputContent(message Message) anthropicTextGenerationInputConte
 				Data:      base64.StdEncoding.EncodeToString([]byte(message.Content)),
 			},
 		}
+	case MessageTypeDocument:
+		c = anthropicTextGenerationInputContent{
+			Type:  AnthropicMessageTypeDocument,
+			Title: message.Name,
+		}
+		if DocumentFormat(message.MimeType) == "pdf" {
+			c.Source = &anthropicBinGenerationInputSource{
+				Type:      "base64",
+				MediaType: message.MimeType,
+				Data:      base64.StdEncoding.EncodeToString([]byte(message.Content)),
+			}
+		} else {
+			// Other formats are read as plain text.
+			c.Source = &anthropicBinGenerationInputSource{
+				Type:      "text",
+				MediaType: "text/plain",
+				Data:      message.Content,
+			}
+		}
 	case "image_url":
 		c = anthropicTextGenerationInputContent{
 			Type: "image",
@@ -482,7 +610,27 @@ func getAnthropicInputContent(message Message) anthropicTextGenerationInputConte
 		c = anthropicTextGenerationInputContent{
 			Type:      "tool_result",
 			ToolUseID: message.ToolUseID,
-			Content:   message.Content,
+			IsError:   message.ToolError,
+		}
+		if len(message.Parts) > 0 {
+			blocks := make([]anthropicTextGenerationInputContent, 0, len(message.Parts))
+			for _, part := range message.Parts {
+				blocks = append(blocks, getAnthropicInputContent(part))
+			}
+			c.Content = blocks
+		} else if message.Content != "" {
+			c.Content = message.Content
+		}
+	case MessageTypeThinking:
+		c = anthropicTextGenerationInputContent{
+			Type:      AnthropicMessageTypeThinking,
+			Thinking:  message.Content,
+			Signature: message.Signature,
+		}
+	case MessageTypeRedactedThinking:
+		c = anthropicTextGenerationInputContent{
+			Type: AnthropicMessageTypeRedacted,
+			Data: message.Content,
 		}
 	case "tool_call":
 		// Handle tool calls from AI messages - convert to tool_use format for Anthropic
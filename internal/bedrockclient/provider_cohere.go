@@ -3,9 +3,11 @@ package bedrockclient
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/tmc/langchaingo/llms"
 )
 
@@ -61,12 +63,138 @@ type cohereTextGenerationOutputGeneration struct {
 	Text string `json:"text"`
 }
 
-func createCohereCompletion(ctx context.Context,
-	client *bedrockruntime.Client,
-	modelID string,
-	messages []Message,
-	options llms.CallOptions,
-) (*llms.ContentResponse, error) {
+// Ref: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-cohere-command-r-plus.html
+
+// Roles of a Command R chat history entry.
+const (
+	CohereRoleUser    = "USER"
+	CohereRoleChatbot = "CHATBOT"
+	CohereRoleSystem  = "SYSTEM"
+	CohereRoleTool    = "TOOL"
+)
+
+// cohereChatInput is the input for the chat of Cohere Command R models.
+type cohereChatInput struct {
+	// The input of the current turn. Required
+	Message string `json:"message"`
+	// The previous turns of the conversation. Optional
+	ChatHistory []cohereChatMessage `json:"chat_history,omitempty"`
+	// The documents the response is grounded on. Optional
+	Documents []map[string]string `json:"documents,omitempty"`
+	// Overrides the default system prompt of the model. Optional
+	Preamble string `json:"preamble,omitempty"`
+	// The maximum number of tokens to generate. Optional
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// Use a lower value to decrease randomness in the response. Optional, default = 0.3
+	Temperature float64 `json:"temperature,omitempty"`
+	// Use a lower value to ignore less probable options. Optional, default = 0.75
+	P float64 `json:"p,omitempty"`
+	// Specify the number of token choices the model uses to generate the next token. Optional, default = 0
+	K int `json:"k,omitempty"`
+	// Sequences that stop the generation. Optional
+	StopSequences []string `json:"stop_sequences,omitempty"`
+	// The tools available to the model. Optional
+	Tools []cohereTool `json:"tools,omitempty"`
+	// The results of the tool calls of the previous turn. Optional
+	ToolResults []cohereToolResult `json:"tool_results,omitempty"`
+}
+
+// cohereChatMessage is a single turn of the chat history.
+type cohereChatMessage struct {
+	// One of: ["USER", "CHATBOT", "SYSTEM", "TOOL"]
+	Role        string             `json:"role"`
+	Message     string             `json:"message,omitempty"`
+	ToolCalls   []cohereToolCall   `json:"tool_calls,omitempty"`
+	ToolResults []cohereToolResult `json:"tool_results,omitempty"`
+}
+
+// cohereTool is the definition of a tool.
+type cohereTool struct {
+	Name                 string                               `json:"name"`
+	Description          string                               `json:"description"`
+	ParameterDefinitions map[string]cohereParameterDefinition `json:"parameter_definitions,omitempty"`
+}
+
+// cohereParameterDefinition describes a single tool parameter.
+type cohereParameterDefinition struct {
+	Description string `json:"description,omitempty"`
+	// Python style type, e.g. "str", "int" or "List[str]".
+	Type     string `json:"type"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// cohereToolCall is a tool call made by the model. Command R does not
+// assign call IDs.
+type cohereToolCall struct {
+	Name       string                 `json:"name"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+// cohereToolResult is the output of a tool call.
+type cohereToolResult struct {
+	Call    cohereToolCall           `json:"call"`
+	Outputs []map[string]interface{} `json:"outputs"`
+}
+
+// cohereCitation links a span of the response text to the documents it
+// is grounded on.
+type cohereCitation struct {
+	Start       int      `json:"start"`
+	End         int      `json:"end"`
+	Text        string   `json:"text"`
+	DocumentIDs []string `json:"document_ids"`
+}
+
+// cohereChatOutput is the output for the chat of Cohere Command R models.
+type cohereChatOutput struct {
+	ResponseID   string           `json:"response_id"`
+	Text         string           `json:"text"`
+	GenerationID string           `json:"generation_id"`
+	FinishReason string           `json:"finish_reason"`
+	ToolCalls    []cohereToolCall `json:"tool_calls"`
+	Citations    []cohereCitation `json:"citations"`
+	Meta         struct {
+		BilledUnits struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"billed_units"`
+	} `json:"meta"`
+	// Generations is only set by the legacy generate API.
+	Generations []*cohereTextGenerationOutputGeneration `json:"generations"`
+}
+
+// cohereProvider serves the Cohere Command models.
+type cohereProvider struct {
+	// command selects the legacy generate API of the Command models,
+	// rather than the chat API of Command R, for every model. The
+	// "cohere-command" variant is only used by pinned models.
+	command bool
+}
+
+func (p cohereProvider) Name() string {
+	if p.command {
+		return "cohere-command"
+	}
+	return "cohere"
+}
+
+func (p cohereProvider) Match(baseModelID string) bool {
+	return !p.command && strings.HasPrefix(baseModelID, "cohere.command")
+}
+
+// isChat reports whether modelID is a Command R model, which uses the
+// chat API instead of the legacy generate API.
+func (p cohereProvider) isChat(modelID string) bool {
+	return !p.command && !matchBaseModel(modelID, func(baseModelID string) bool {
+		return !strings.HasPrefix(baseModelID, "cohere.command-r")
+	})
+}
+
+func (p cohereProvider) BuildRequest(modelID string, messages []Message, options llms.CallOptions) ([]byte, error) {
+	if p.isChat(modelID) {
+		return cohereChatInputToJSON(messages, options)
+	}
+
 	txt := processInputMessagesGeneric(messages)
 
 	input := &cohereTextGenerationInput{
@@ -79,28 +207,19 @@ func createCohereCompletion(ctx context.Context,
 		NumGenerations: options.CandidateCount,
 	}
 
-	body, err := json.Marshal(input)
-	if err != nil {
-		return nil, err
-	}
-
-	modelInput := &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(modelID),
-		Accept:      aws.String("*/*"),
-		ContentType: aws.String("application/json"),
-		Body:        body,
-	}
-	resp, err := client.InvokeModel(ctx, modelInput)
-	if err != nil {
-		return nil, err
-	}
+	return json.Marshal(input)
+}
 
-	var output cohereTextGenerationOutput
+func (cohereProvider) ParseResponse(body []byte) (*llms.ContentResponse, error) {
+	var output cohereChatOutput
 
-	err = json.Unmarshal(resp.Body, &output)
+	err := json.Unmarshal(body, &output)
 	if err != nil {
 		return nil, err
 	}
+	if output.Generations == nil {
+		return parseCohereChatOutput(&output)
+	}
 
 	choices := make([]*llms.ContentChoice, len(output.Generations))
 
@@ -119,3 +238,363 @@ func createCohereCompletion(ctx context.Context,
 		Choices: choices,
 	}, nil
 }
+
+func (cohereProvider) NewStreamParser(modelID string, options llms.CallOptions) StreamParser {
+	return &cohereStreamParser{
+		options: options,
+		choice: &llms.ContentChoice{
+			GenerationInfo: map[string]interface{}{
+				"input_tokens":  0,
+				"output_tokens": 0,
+			},
+		},
+	}
+}
+
+func cohereChatInputToJSON(messages []Message, options llms.CallOptions) ([]byte, error) {
+	history, preamble, documents, err := processInputMessagesCohere(messages)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := options.Metadata[MetadataCohereDocuments]; ok {
+		extra, ok := v.([]map[string]string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be []map[string]string, got %T", MetadataCohereDocuments, v)
+		}
+		documents = append(extra[:len(extra):len(extra)], documents...)
+	}
+	tools, err := getCohereTools(options)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &cohereChatInput{
+		Preamble:      preamble,
+		MaxTokens:     options.MaxTokens,
+		Temperature:   options.Temperature,
+		P:             options.TopP,
+		K:             options.TopK,
+		StopSequences: options.StopWords,
+		Tools:         tools,
+		Documents:     documents,
+	}
+
+	// The last turn is sent as the current message, or as the tool
+	// results the model asked for.
+	if n := len(history); n > 0 {
+		switch last := history[n-1]; last.Role {
+		case CohereRoleUser:
+			input.Message = last.Message
+			history = history[:n-1]
+		case CohereRoleTool:
+			input.ToolResults = last.ToolResults
+			history = history[:n-1]
+		}
+	}
+	input.ChatHistory = history
+
+	return json.Marshal(input)
+}
+
+// processInputMessagesCohere groups the messages into chat history turns
+// and returns them with the preamble and the documents to ground on.
+func processInputMessagesCohere(messages []Message) ([]cohereChatMessage, string, []map[string]string, error) {
+	var preamble string
+	var history []cohereChatMessage
+	var documents []map[string]string
+	// Tool results reference their call by ID, Command R by name and
+	// parameters.
+	calls := make(map[string]cohereToolCall)
+	for _, message := range messages {
+		role, err := getCohereRole(message.Role)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if role == CohereRoleSystem {
+			preamble += message.Content
+			continue
+		}
+		if message.Type == MessageTypeDocument {
+			// Command R reads documents as text snippets.
+			if !strings.HasPrefix(message.MimeType, "text/") || message.S3Location != nil {
+				return nil, "", nil, fmt.Errorf("unsupported document type: %s", message.MimeType)
+			}
+			documents = append(documents, map[string]string{"title": message.Name, "snippet": message.Content})
+			continue
+		}
+		if n := len(history); n == 0 || history[n-1].Role != role {
+			history = append(history, cohereChatMessage{Role: role})
+		}
+		turn := &history[len(history)-1]
+
+		switch message.Type {
+		case "text":
+			if turn.Message != "" {
+				turn.Message += "\n"
+			}
+			turn.Message += message.Content
+		case "tool_call":
+			call := cohereToolCall{Name: message.ToolName, Parameters: map[string]interface{}{}}
+			if message.ToolArgs != "" {
+				if err := json.Unmarshal([]byte(message.ToolArgs), &call.Parameters); err != nil {
+					return nil, "", nil, fmt.Errorf("failed to decode tool arguments: %w", err)
+				}
+			}
+			calls[message.ToolCallID] = call
+			turn.ToolCalls = append(turn.ToolCalls, call)
+		case "tool_result":
+			call, ok := calls[message.ToolUseID]
+			if !ok {
+				return nil, "", nil, fmt.Errorf("tool result %q without matching tool call", message.ToolUseID)
+			}
+			var output map[string]interface{}
+			if err := json.Unmarshal([]byte(message.Content), &output); err != nil {
+				output = map[string]interface{}{"result": message.Content}
+			}
+			turn.ToolResults = append(turn.ToolResults, cohereToolResult{
+				Call:    call,
+				Outputs: []map[string]interface{}{output},
+			})
+		default:
+			return nil, "", nil, fmt.Errorf("unsupported message type: %s", message.Type)
+		}
+	}
+	return history, preamble, documents, nil
+}
+
+// process the role of the message to cohere supported role.
+func getCohereRole(role ChatMessageType) (string, error) {
+	switch role {
+	case ChatMessageTypeSystem:
+		return CohereRoleSystem, nil
+	case ChatMessageTypeAI:
+		return CohereRoleChatbot, nil
+	case ChatMessageTypeGeneric, ChatMessageTypeHuman:
+		return CohereRoleUser, nil
+	case ChatMessageTypeFunction, ChatMessageTypeTool:
+		return CohereRoleTool, nil
+	default:
+		return "", errors.New("role not supported")
+	}
+}
+
+// cohereSchema is the part of a JSON schema that Command R tools support.
+type cohereSchema struct {
+	Type        string                  `json:"type"`
+	Description string                  `json:"description"`
+	Items       *cohereSchema           `json:"items"`
+	Properties  map[string]cohereSchema `json:"properties"`
+	Required    []string                `json:"required"`
+}
+
+func getCohereTools(options llms.CallOptions) ([]cohereTool, error) {
+	if len(options.Tools) == 0 || options.ToolChoice == "none" {
+		return nil, nil
+	}
+	if err := checkToolChoiceAuto("Command R", options.ToolChoice); err != nil {
+		return nil, err
+	}
+
+	bedrockTools, err := convertToolsToBedrockTools(options.Tools)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert tools: %w", err)
+	}
+	tools := make([]cohereTool, 0, len(bedrockTools))
+	for _, tool := range bedrockTools {
+		var schema cohereSchema
+		if tool.InputSchema != nil {
+			raw, err := json.Marshal(tool.InputSchema)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode schema of tool %s: %w", tool.Name, err)
+			}
+			if err := json.Unmarshal(raw, &schema); err != nil {
+				return nil, fmt.Errorf("failed to decode schema of tool %s: %w", tool.Name, err)
+			}
+		}
+
+		params := make(map[string]cohereParameterDefinition, len(schema.Properties))
+		for name, prop := range schema.Properties {
+			params[name] = cohereParameterDefinition{
+				Description: prop.Description,
+				Type:        cohereParameterType(prop),
+				Required:    slices.Contains(schema.Required, name),
+			}
+		}
+		tools = append(tools, cohereTool{
+			Name:                 tool.Name,
+			Description:          tool.Description,
+			ParameterDefinitions: params,
+		})
+	}
+	return tools, nil
+}
+
+// cohereParameterType converts a JSON schema type to the Python style type
+// names Command R was trained on.
+func cohereParameterType(schema cohereSchema) string {
+	switch strings.ToLower(schema.Type) {
+	case "string":
+		return "str"
+	case "integer":
+		return "int"
+	case "number":
+		return "float"
+	case "boolean":
+		return "bool"
+	case "array":
+		if schema.Items != nil {
+			return "List[" + cohereParameterType(*schema.Items) + "]"
+		}
+		return "list"
+	case "object":
+		return "Dict"
+	default:
+		return "str"
+	}
+}
+
+func parseCohereChatOutput(output *cohereChatOutput) (*llms.ContentResponse, error) {
+	choice := &llms.ContentChoice{
+		Content:    output.Text,
+		StopReason: output.FinishReason,
+		GenerationInfo: map[string]interface{}{
+			"generation_id": output.GenerationID,
+			"input_tokens":  output.Meta.BilledUnits.InputTokens,
+			"output_tokens": output.Meta.BilledUnits.OutputTokens,
+		},
+	}
+	for _, call := range output.ToolCalls {
+		toolCall, err := convertCohereToolCall(call)
+		if err != nil {
+			return nil, err
+		}
+		choice.ToolCalls = append(choice.ToolCalls, toolCall)
+	}
+	if len(choice.ToolCalls) > 0 {
+		choice.FuncCall = choice.ToolCalls[0].FunctionCall
+	}
+	if len(output.Citations) > 0 {
+		choice.GenerationInfo[GenerationInfoCitations] = convertCohereCitations(output.Citations)
+	}
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{choice},
+	}, nil
+}
+
+// convertCohereToolCall converts a tool call of the model. Command R does
+// not assign call IDs, so one is synthesized.
+func convertCohereToolCall(call cohereToolCall) (llms.ToolCall, error) {
+	if call.Parameters == nil {
+		call.Parameters = map[string]interface{}{}
+	}
+	toolCall, err := convertBedrockToolCallToLLMToolCall(BedrockToolCall{
+		Type:  AnthropicMessageTypeToolUse,
+		ID:    newToolCallID(),
+		Name:  call.Name,
+		Input: call.Parameters,
+	})
+	if err != nil {
+		return llms.ToolCall{}, fmt.Errorf("failed to convert tool call: %w", err)
+	}
+	return toolCall, nil
+}
+
+func convertCohereCitations(citations []cohereCitation) []Citation {
+	result := make([]Citation, len(citations))
+	for i, c := range citations {
+		result[i] = Citation{Start: c.Start, End: c.End, Text: c.Text, DocumentIDs: c.DocumentIDs}
+	}
+	return result
+}
+
+// Types of the events of a streamed Command R chat.
+const (
+	CohereEventStreamStart         = "stream-start"
+	CohereEventTextGeneration      = "text-generation"
+	CohereEventCitationGeneration  = "citation-generation"
+	CohereEventToolCallsGeneration = "tool-calls-generation"
+	CohereEventStreamEnd           = "stream-end"
+)
+
+// cohereStreamChunk is a single chunk of a streamed response. Command R
+// chunks are typed events, legacy generate chunks only carry text.
+type cohereStreamChunk struct {
+	EventType    string             `json:"event_type"`
+	GenerationID string             `json:"generation_id"`
+	Text         string             `json:"text"`
+	IsFinished   bool               `json:"is_finished"`
+	FinishReason string             `json:"finish_reason"`
+	ToolCalls    []cohereToolCall   `json:"tool_calls"`
+	Citations    []cohereCitation   `json:"citations"`
+	Response     *cohereChatOutput  `json:"response"`
+	Metrics      *invocationMetrics `json:"amazon-bedrock-invocationMetrics"`
+}
+
+// cohereStreamParser accumulates the chunks of a streamed response into a
+// single choice.
+type cohereStreamParser struct {
+	options   llms.CallOptions
+	choice    *llms.ContentChoice
+	citations []Citation
+}
+
+func (p *cohereStreamParser) ParseChunk(ctx context.Context, chunk []byte) error {
+	var resp cohereStreamChunk
+	if err := json.Unmarshal(chunk, &resp); err != nil {
+		return err
+	}
+
+	switch resp.EventType {
+	case CohereEventStreamStart:
+		p.choice.GenerationInfo["generation_id"] = resp.GenerationID
+	case CohereEventTextGeneration, "":
+		if resp.Text != "" {
+			if err := p.options.StreamingFunc(ctx, []byte(resp.Text)); err != nil {
+				return err
+			}
+			p.choice.Content += resp.Text
+		}
+	case CohereEventCitationGeneration:
+		p.citations = append(p.citations, convertCohereCitations(resp.Citations)...)
+	case CohereEventToolCallsGeneration:
+		// Tool calls are sent complete, once the model has generated them.
+		for _, call := range resp.ToolCalls {
+			toolCall, err := convertCohereToolCall(call)
+			if err != nil {
+				return err
+			}
+			p.choice.ToolCalls = append(p.choice.ToolCalls, toolCall)
+			if err := emitStreamEvent(ctx, p.options, StreamEvent{Type: StreamEventToolCallStart, ToolCall: cloneToolCall(toolCall)}); err != nil {
+				return err
+			}
+			if err := emitStreamEvent(ctx, p.options, StreamEvent{Type: StreamEventToolCallDone, ToolCall: cloneToolCall(toolCall)}); err != nil {
+				return err
+			}
+		}
+	case CohereEventStreamEnd:
+		if resp.Response != nil {
+			p.choice.GenerationInfo["input_tokens"] = resp.Response.Meta.BilledUnits.InputTokens
+			p.choice.GenerationInfo["output_tokens"] = resp.Response.Meta.BilledUnits.OutputTokens
+		}
+	}
+	if resp.IsFinished && resp.FinishReason != "" {
+		p.choice.StopReason = resp.FinishReason
+	}
+
+	if resp.Metrics != nil {
+		return resp.Metrics.reportUsage(ctx, p.options, p.choice)
+	}
+	return nil
+}
+
+func (p *cohereStreamParser) Result() (*llms.ContentResponse, error) {
+	if len(p.choice.ToolCalls) > 0 {
+		p.choice.FuncCall = p.choice.ToolCalls[0].FunctionCall
+	}
+	if len(p.citations) > 0 {
+		p.choice.GenerationInfo[GenerationInfoCitations] = p.citations
+	}
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{p.choice},
+	}, nil
+}
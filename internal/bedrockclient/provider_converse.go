@@ -0,0 +1,650 @@
+package bedrockclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Ref: https://docs.aws.amazon.com/bedrock/latest/userguide/conversation-inference.html
+// Also: https://docs.aws.amazon.com/bedrock/latest/APIReference/API_runtime_Converse.html
+
+// Role attribute for the converse message.
+const (
+	ConverseSystem        = "system"
+	ConverseRoleUser      = "user"
+	ConverseRoleAssistant = "assistant"
+)
+
+// CreateConverseCompletion creates a new completion response through the
+// model-agnostic Converse / ConverseStream API instead of the
+// provider-specific InvokeModel bodies used by CreateCompletion.
+func (c *Client) CreateConverseCompletion(ctx context.Context,
+	modelID string,
+	messages []Message,
+	options llms.CallOptions,
+) (*llms.ContentResponse, error) {
+	// Unknown model IDs are sent as they are, Converse may still serve them.
+	provider, _ := c.resolveProvider(modelID)
+	messages, err := c.prepareMessages(ctx, provider, modelID, messages)
+	if err != nil {
+		return nil, err
+	}
+	if !readsToolErrorStatus(provider) {
+		// The text of the result still describes the error.
+		messages, _ = replaceMessages(messages, func(message Message) (Message, bool, error) {
+			changed := message.ToolError
+			message.ToolError = false
+			return message, changed, nil
+		})
+	}
+
+	// The response schema is emulated for the families the InvokeModel
+	// provider would emulate it for.
+	var output *structuredOutput
+	if provider != nil && supportsStructuredOutput(provider) {
+		if output, err = getStructuredOutput(options); err != nil {
+			return nil, err
+		}
+	}
+	if output == nil {
+		return createConverseCompletion(ctx, c.client, provider, modelID, messages, options)
+	}
+	resp, err := createConverseCompletion(ctx, c.client, provider, modelID, messages, output.apply(options))
+	if err != nil {
+		return nil, err
+	}
+	if err := output.unwrap(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func createConverseCompletion(ctx context.Context,
+	client *bedrockruntime.Client,
+	provider Provider,
+	modelID string,
+	messages []Message,
+	options llms.CallOptions,
+) (*llms.ContentResponse, error) {
+	inputMessages, system, err := processInputMessagesConverse(provider, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	inferenceConfig, fields := getConverseInferenceConfig(provider, options)
+	toolConfig, err := getConverseToolConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	system = applyConverseCachePoints(provider, inputMessages, system, toolConfig, options)
+
+	var additionalFields document.Interface
+	if len(fields) > 0 {
+		additionalFields = document.NewLazyDocument(fields)
+	}
+
+	guardrail := getGuardrail(options)
+	guardrailTrace := types.GuardrailTraceDisabled
+	if guardrail != nil && guardrail.Trace {
+		guardrailTrace = types.GuardrailTraceEnabled
+	}
+
+	if options.StreamingFunc != nil {
+		input := &bedrockruntime.ConverseStreamInput{
+			ModelId:                      aws.String(modelID),
+			Messages:                     inputMessages,
+			System:                       system,
+			InferenceConfig:              inferenceConfig,
+			ToolConfig:                   toolConfig,
+			AdditionalModelRequestFields: additionalFields,
+		}
+		if guardrail != nil {
+			input.GuardrailConfig = &types.GuardrailStreamConfiguration{
+				GuardrailIdentifier:  aws.String(guardrail.Identifier),
+				GuardrailVersion:     aws.String(guardrail.Version),
+				Trace:                guardrailTrace,
+				StreamProcessingMode: types.GuardrailStreamProcessingMode(guardrail.StreamProcessingMode),
+			}
+		}
+		return parseConverseStreamResponse(ctx, client, input, options)
+	}
+
+	input := &bedrockruntime.ConverseInput{
+		ModelId:                      aws.String(modelID),
+		Messages:                     inputMessages,
+		System:                       system,
+		InferenceConfig:              inferenceConfig,
+		ToolConfig:                   toolConfig,
+		AdditionalModelRequestFields: additionalFields,
+	}
+	if guardrail != nil {
+		input.GuardrailConfig = &types.GuardrailConfiguration{
+			GuardrailIdentifier: aws.String(guardrail.Identifier),
+			GuardrailVersion:    aws.String(guardrail.Version),
+			Trace:               guardrailTrace,
+		}
+	}
+	resp, err := client.Converse(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	output, ok := resp.Output.(*types.ConverseOutputMemberMessage)
+	if !ok {
+		return nil, errors.New("no results")
+	}
+
+	choice := &llms.ContentChoice{
+		StopReason:     string(resp.StopReason),
+		GenerationInfo: converseUsageToGenerationInfo(resp.Usage),
+	}
+
+	var toolCalls []llms.ToolCall
+	var thinkingBlocks []ThinkingBlock
+	for _, block := range output.Value.Content {
+		switch b := block.(type) {
+		case *types.ContentBlockMemberReasoningContent:
+			switch r := b.Value.(type) {
+			case *types.ReasoningContentBlockMemberReasoningText:
+				thinkingBlocks = append(thinkingBlocks, ThinkingBlock{
+					Text:      aws.ToString(r.Value.Text),
+					Signature: aws.ToString(r.Value.Signature),
+				})
+			case *types.ReasoningContentBlockMemberRedactedContent:
+				thinkingBlocks = append(thinkingBlocks, ThinkingBlock{RedactedData: string(r.Value)})
+			}
+		case *types.ContentBlockMemberText:
+			choice.Content += b.Value
+		case *types.ContentBlockMemberToolUse:
+			var input interface{}
+			if b.Value.Input != nil {
+				if err := b.Value.Input.UnmarshalSmithyDocument(&input); err != nil {
+					return nil, fmt.Errorf("failed to decode tool input: %w", err)
+				}
+			}
+			if input == nil {
+				input = map[string]interface{}{}
+			}
+			toolCall, err := convertBedrockToolCallToLLMToolCall(BedrockToolCall{
+				Type:  AnthropicMessageTypeToolUse,
+				ID:    aws.ToString(b.Value.ToolUseId),
+				Name:  aws.ToString(b.Value.Name),
+				Input: input,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert tool call: %w", err)
+			}
+			toolCalls = append(toolCalls, toolCall)
+		}
+	}
+
+	choice.ToolCalls = toolCalls
+	if len(toolCalls) > 0 {
+		choice.FuncCall = toolCalls[0].FunctionCall
+	}
+	setThinkingBlocks(choice, thinkingBlocks)
+
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{choice},
+	}, nil
+}
+
+// getConverseInferenceConfig returns the inference configuration of a
+// Converse request, and the model specific request fields that Converse
+// has no first-class field for.
+func getConverseInferenceConfig(provider Provider, options llms.CallOptions) (*types.InferenceConfiguration, map[string]interface{}) {
+	maxTokens := getMaxTokens(options.MaxTokens, 2048)
+	// Extended thinking is only known to the Anthropic models, others
+	// reject the field.
+	var budget int
+	if provider != nil && provider.Name() == "anthropic" {
+		budget = getThinkingBudget(options)
+	}
+	if budget > 0 && maxTokens <= budget {
+		maxTokens += budget
+	}
+
+	inferenceConfig := &types.InferenceConfiguration{
+		MaxTokens:     aws.Int32(int32(maxTokens)),
+		StopSequences: options.StopWords,
+	}
+	// Extended thinking is incompatible with custom sampling parameters.
+	if options.Temperature != 0 && budget == 0 {
+		inferenceConfig.Temperature = aws.Float32(float32(options.Temperature))
+	}
+	if options.TopP != 0 && budget == 0 {
+		inferenceConfig.TopP = aws.Float32(float32(options.TopP))
+	}
+
+	fields := map[string]interface{}{}
+	if beta := getAnthropicBeta(options); len(beta) > 0 {
+		fields["anthropic_beta"] = beta
+	}
+	// Converse has no top k, the families that take it read it from a
+	// field of their own. It is dropped for the others.
+	if options.TopK != 0 && budget == 0 && provider != nil {
+		switch provider.Name() {
+		case "anthropic":
+			fields["top_k"] = options.TopK
+		case "nova":
+			fields["inferenceConfig"] = map[string]interface{}{"topK": options.TopK}
+		case "cohere", "cohere-command":
+			fields["k"] = options.TopK
+		}
+	}
+	if budget > 0 {
+		fields["thinking"] = anthropicThinkingConfig{Type: "enabled", BudgetTokens: budget}
+	}
+	return inferenceConfig, fields
+}
+
+func parseConverseStreamResponse(ctx context.Context, client *bedrockruntime.Client, input *bedrockruntime.ConverseStreamInput, options llms.CallOptions) (*llms.ContentResponse, error) {
+	output, err := client.ConverseStream(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	stream := output.GetStream()
+	if stream == nil {
+		return nil, errors.New("no stream")
+	}
+	return readConverseStream(ctx, stream, options)
+}
+
+// readConverseStream feeds the events of a ConverseStream stream to a
+// converseStreamParser and returns the aggregated response.
+func readConverseStream(ctx context.Context, stream eventStream[types.ConverseStreamOutput], options llms.CallOptions) (*llms.ContentResponse, error) {
+	parser := newConverseStreamParser(options)
+	err := readStream(ctx, stream, func(e types.ConverseStreamOutput) error {
+		return parser.parseEvent(ctx, e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parser.result(), nil
+}
+
+// converseStreamParser accumulates the events of a ConverseStream
+// response into a single choice.
+type converseStreamParser struct {
+	options llms.CallOptions
+	choice  *llms.ContentChoice
+	// Tool calls are keyed by content block index so that deltas land
+	// on the call that was opened by the matching block start event.
+	toolCallIndex map[int32]int
+	// Reasoning blocks have no start event; they open on their first delta.
+	thinking      []ThinkingBlock
+	thinkingIndex map[int32]int
+}
+
+func newConverseStreamParser(options llms.CallOptions) *converseStreamParser {
+	return &converseStreamParser{
+		options: options,
+		choice: &llms.ContentChoice{GenerationInfo: map[string]interface{}{
+			"input_tokens":  0,
+			"output_tokens": 0,
+		}},
+		toolCallIndex: make(map[int32]int),
+		thinkingIndex: make(map[int32]int),
+	}
+}
+
+func (p *converseStreamParser) parseEvent(ctx context.Context, e types.ConverseStreamOutput) error {
+	switch v := e.(type) {
+	case *types.ConverseStreamOutputMemberContentBlockStart:
+		if start, ok := v.Value.Start.(*types.ContentBlockStartMemberToolUse); ok {
+			p.toolCallIndex[aws.ToInt32(v.Value.ContentBlockIndex)] = len(p.choice.ToolCalls)
+			toolCall := llms.ToolCall{
+				ID:   aws.ToString(start.Value.ToolUseId),
+				Type: "function",
+				FunctionCall: &llms.FunctionCall{
+					Name: aws.ToString(start.Value.Name),
+				},
+			}
+			p.choice.ToolCalls = append(p.choice.ToolCalls, toolCall)
+			return emitStreamEvent(ctx, p.options, StreamEvent{Type: StreamEventToolCallStart, ToolCall: cloneToolCall(toolCall)})
+		}
+	case *types.ConverseStreamOutputMemberContentBlockDelta:
+		return p.parseDelta(ctx, aws.ToInt32(v.Value.ContentBlockIndex), v.Value.Delta)
+	case *types.ConverseStreamOutputMemberContentBlockStop:
+		if i, ok := p.toolCallIndex[aws.ToInt32(v.Value.ContentBlockIndex)]; ok {
+			// A tool call without arguments streams no deltas at all.
+			if p.choice.ToolCalls[i].FunctionCall.Arguments == "" {
+				p.choice.ToolCalls[i].FunctionCall.Arguments = "{}"
+			}
+			return emitStreamEvent(ctx, p.options, StreamEvent{Type: StreamEventToolCallDone, ToolCall: cloneToolCall(p.choice.ToolCalls[i])})
+		}
+	case *types.ConverseStreamOutputMemberMessageStop:
+		p.choice.StopReason = string(v.Value.StopReason)
+	case *types.ConverseStreamOutputMemberMetadata:
+		if v.Value.Usage != nil {
+			p.choice.GenerationInfo = converseUsageToGenerationInfo(v.Value.Usage)
+			return emitStreamEvent(ctx, p.options, StreamEvent{Type: StreamEventUsage, Usage: copyUsage(p.choice.GenerationInfo)})
+		}
+	}
+	return nil
+}
+
+func (p *converseStreamParser) parseDelta(ctx context.Context, blockIndex int32, delta types.ContentBlockDelta) error {
+	switch delta := delta.(type) {
+	case *types.ContentBlockDeltaMemberReasoningContent:
+		i, ok := p.thinkingIndex[blockIndex]
+		if !ok {
+			i = len(p.thinking)
+			p.thinkingIndex[blockIndex] = i
+			p.thinking = append(p.thinking, ThinkingBlock{})
+		}
+		switch r := delta.Value.(type) {
+		case *types.ReasoningContentBlockDeltaMemberText:
+			if p.options.StreamingReasoningFunc != nil {
+				if err := p.options.StreamingReasoningFunc(ctx, []byte(r.Value), nil); err != nil {
+					return err
+				}
+			}
+			p.thinking[i].Text += r.Value
+		case *types.ReasoningContentBlockDeltaMemberSignature:
+			p.thinking[i].Signature += r.Value
+		case *types.ReasoningContentBlockDeltaMemberRedactedContent:
+			p.thinking[i].RedactedData += string(r.Value)
+		}
+	case *types.ContentBlockDeltaMemberText:
+		if err := p.options.StreamingFunc(ctx, []byte(delta.Value)); err != nil {
+			return err
+		}
+		p.choice.Content += delta.Value
+	case *types.ContentBlockDeltaMemberToolUse:
+		i, ok := p.toolCallIndex[blockIndex]
+		if !ok {
+			return errors.New("tool use delta without matching block start")
+		}
+		p.choice.ToolCalls[i].FunctionCall.Arguments += aws.ToString(delta.Value.Input)
+		return emitStreamEvent(ctx, p.options, StreamEvent{
+			Type:           StreamEventToolCallDelta,
+			ToolCall:       cloneToolCall(p.choice.ToolCalls[i]),
+			ArgumentsDelta: aws.ToString(delta.Value.Input),
+		})
+	}
+	return nil
+}
+
+func (p *converseStreamParser) result() *llms.ContentResponse {
+	// A tool call without arguments streams no deltas at all.
+	for i := range p.choice.ToolCalls {
+		if p.choice.ToolCalls[i].FunctionCall.Arguments == "" {
+			p.choice.ToolCalls[i].FunctionCall.Arguments = "{}"
+		}
+	}
+	if len(p.choice.ToolCalls) > 0 {
+		p.choice.FuncCall = p.choice.ToolCalls[0].FunctionCall
+	}
+	setThinkingBlocks(p.choice, p.thinking)
+
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{p.choice},
+	}
+}
+
+func converseUsageToGenerationInfo(usage *types.TokenUsage) map[string]interface{} {
+	info := map[string]interface{}{
+		"input_tokens":  0,
+		"output_tokens": 0,
+	}
+	if usage == nil {
+		return info
+	}
+	info["input_tokens"] = int(aws.ToInt32(usage.InputTokens))
+	info["output_tokens"] = int(aws.ToInt32(usage.OutputTokens))
+	info[GenerationInfoCacheReadTokens] = int(aws.ToInt32(usage.CacheReadInputTokens))
+	info[GenerationInfoCacheCreationTokens] = int(aws.ToInt32(usage.CacheWriteInputTokens))
+	return info
+}
+
+// getConverseToolConfig converts the tools and tool choice of the call
+// options to the Converse tool configuration.
+func getConverseToolConfig(options llms.CallOptions) (*types.ToolConfiguration, error) {
+	if len(options.Tools) == 0 {
+		return nil, nil
+	}
+
+	bedrockTools, err := convertToolsToBedrockTools(options.Tools)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert tools: %w", err)
+	}
+
+	toolConfig := &types.ToolConfiguration{}
+	for _, tool := range bedrockTools {
+		spec := types.ToolSpecification{
+			Name:        aws.String(tool.Name),
+			InputSchema: &types.ToolInputSchemaMemberJson{Value: document.NewLazyDocument(tool.InputSchema)},
+		}
+		if tool.Description != "" {
+			spec.Description = aws.String(tool.Description)
+		}
+		toolConfig.Tools = append(toolConfig.Tools, &types.ToolMemberToolSpec{Value: spec})
+	}
+
+	toolChoice, err := convertToolChoiceToBedrockToolChoice(options.ToolChoice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert tool choice: %w", err)
+	}
+	if toolChoice != nil {
+		switch toolChoice.Type {
+		case "auto":
+			toolConfig.ToolChoice = &types.ToolChoiceMemberAuto{}
+		case "any":
+			toolConfig.ToolChoice = &types.ToolChoiceMemberAny{}
+		case "tool":
+			toolConfig.ToolChoice = &types.ToolChoiceMemberTool{Value: types.SpecificToolChoice{Name: aws.String(toolChoice.Name)}}
+		}
+	}
+
+	return toolConfig, nil
+}
+
+// process the input messages to converse supported input
+// returns the input messages and system content blocks.
+func processInputMessagesConverse(provider Provider, messages []Message) ([]types.Message, []types.SystemContentBlock, error) {
+	// Converse requires strictly alternating user / assistant turns, so
+	// messages are grouped by their converse role rather than by their
+	// chat message type (a tool result and a human follow-up share a turn).
+	if err := converseDocumentLimits.check(messages); err != nil {
+		return nil, nil, err
+	}
+	if err := checkVideos(messages); err != nil {
+		return nil, nil, err
+	}
+	messages = nameDocuments(messages)
+
+	inputMessages := make([]types.Message, 0, len(messages))
+	var system []types.SystemContentBlock
+	for _, message := range messages {
+		// Only Claude replays reasoning blocks, other families reject the
+		// signatures of foreign models.
+		if (message.Type == MessageTypeThinking || message.Type == MessageTypeRedactedThinking) &&
+			(provider == nil || provider.Name() != "anthropic") {
+			continue
+		}
+		role, err := getConverseRole(message.Role)
+		if err != nil {
+			return nil, nil, err
+		}
+		if role == ConverseSystem {
+			if message.Type != AnthropicMessageTypeText {
+				return nil, nil, errors.New("system prompt must be text")
+			}
+			system = append(system, &types.SystemContentBlockMemberText{Value: message.Content})
+			continue
+		}
+
+		block, err := getConverseInputContent(message)
+		if err != nil {
+			return nil, nil, err
+		}
+		if block == nil {
+			continue
+		}
+
+		conversationRole := types.ConversationRole(role)
+		if n := len(inputMessages); n > 0 && inputMessages[n-1].Role == conversationRole {
+			inputMessages[n-1].Content = append(inputMessages[n-1].Content, block)
+			continue
+		}
+		inputMessages = append(inputMessages, types.Message{
+			Role:    conversationRole,
+			Content: []types.ContentBlock{block},
+		})
+	}
+	return inputMessages, system, nil
+}
+
+// getConverseToolResultContent returns the blocks of a tool result, which
+// are its text unless it returns media.
+func getConverseToolResultContent(message Message) ([]types.ToolResultContentBlock, error) {
+	if len(message.Parts) == 0 {
+		return []types.ToolResultContentBlock{
+			&types.ToolResultContentBlockMemberText{Value: message.Content},
+		}, nil
+	}
+	content := make([]types.ToolResultContentBlock, 0, len(message.Parts))
+	for _, part := range message.Parts {
+		block, err := getConverseInputContent(part)
+		if err != nil {
+			return nil, err
+		}
+		switch block := block.(type) {
+		case *types.ContentBlockMemberText:
+			content = append(content, &types.ToolResultContentBlockMemberText{Value: block.Value})
+		case *types.ContentBlockMemberImage:
+			content = append(content, &types.ToolResultContentBlockMemberImage{Value: block.Value})
+		case *types.ContentBlockMemberDocument:
+			content = append(content, &types.ToolResultContentBlockMemberDocument{Value: block.Value})
+		case *types.ContentBlockMemberVideo:
+			content = append(content, &types.ToolResultContentBlockMemberVideo{Value: block.Value})
+		case nil:
+		default:
+			return nil, fmt.Errorf("unsupported tool result block: %T", block)
+		}
+	}
+	return content, nil
+}
+
+// readsToolErrorStatus reports whether the models of p take the status of
+// Converse tool results. Others reject it.
+func readsToolErrorStatus(p Provider) bool {
+	return p != nil && (p.Name() == "anthropic" || p.Name() == "nova")
+}
+
+func converseS3Location(location *S3Location) types.S3Location {
+	s3Location := types.S3Location{Uri: aws.String(location.URI)}
+	if location.BucketOwner != "" {
+		s3Location.BucketOwner = aws.String(location.BucketOwner)
+	}
+	return s3Location
+}
+
+// process the role of the message to converse supported role.
+func getConverseRole(role ChatMessageType) (string, error) {
+	switch role {
+	case ChatMessageTypeSystem:
+		return ConverseSystem, nil
+	case ChatMessageTypeAI:
+		return ConverseRoleAssistant, nil
+	case ChatMessageTypeGeneric, ChatMessageTypeHuman:
+		return ConverseRoleUser, nil
+	case ChatMessageTypeFunction, ChatMessageTypeTool:
+		return ConverseRoleUser, nil // Tool results are sent as user messages
+	default:
+		return "", errors.New("role not supported")
+	}
+}
+
+func getConverseInputContent(message Message) (types.ContentBlock, error) {
+	switch message.Type {
+	case AnthropicMessageTypeText:
+		if message.Content == "" {
+			return nil, nil
+		}
+		return &types.ContentBlockMemberText{Value: message.Content}, nil
+	case AnthropicMessageTypeImage:
+		format := mimeTypeToFormat(message.MimeType)
+		if format == "" {
+			return nil, fmt.Errorf("unsupported image media type: %s", message.MimeType)
+		}
+		var source types.ImageSource = &types.ImageSourceMemberBytes{Value: []byte(message.Content)}
+		if message.S3Location != nil {
+			source = &types.ImageSourceMemberS3Location{Value: converseS3Location(message.S3Location)}
+		}
+		return &types.ContentBlockMemberImage{Value: types.ImageBlock{
+			Format: types.ImageFormat(format),
+			Source: source,
+		}}, nil
+	case MessageTypeDocument:
+		var source types.DocumentSource = &types.DocumentSourceMemberBytes{Value: []byte(message.Content)}
+		if message.S3Location != nil {
+			source = &types.DocumentSourceMemberS3Location{Value: converseS3Location(message.S3Location)}
+		}
+		return &types.ContentBlockMemberDocument{Value: types.DocumentBlock{
+			Format: types.DocumentFormat(DocumentFormat(message.MimeType)),
+			Name:   aws.String(message.Name),
+			Source: source,
+		}}, nil
+	case MessageTypeVideo:
+		var source types.VideoSource = &types.VideoSourceMemberBytes{Value: []byte(message.Content)}
+		if message.S3Location != nil {
+			source = &types.VideoSourceMemberS3Location{Value: converseS3Location(message.S3Location)}
+		}
+		return &types.ContentBlockMemberVideo{Value: types.VideoBlock{
+			Format: types.VideoFormat(VideoFormat(message.MimeType)),
+			Source: source,
+		}}, nil
+	case "image_url":
+		return nil, errors.New("image URLs are not supported by the Converse API")
+	case "tool_result":
+		content, err := getConverseToolResultContent(message)
+		if err != nil {
+			return nil, err
+		}
+		result := types.ToolResultBlock{
+			ToolUseId: aws.String(message.ToolUseID),
+			Content:   content,
+		}
+		if message.ToolError {
+			result.Status = types.ToolResultStatusError
+		}
+		return &types.ContentBlockMemberToolResult{Value: result}, nil
+	case MessageTypeThinking:
+		reasoning := types.ReasoningTextBlock{Text: aws.String(message.Content)}
+		if message.Signature != "" {
+			reasoning.Signature = aws.String(message.Signature)
+		}
+		return &types.ContentBlockMemberReasoningContent{
+			Value: &types.ReasoningContentBlockMemberReasoningText{Value: reasoning},
+		}, nil
+	case MessageTypeRedactedThinking:
+		return &types.ContentBlockMemberReasoningContent{
+			Value: &types.ReasoningContentBlockMemberRedactedContent{Value: []byte(message.Content)},
+		}, nil
+	case "tool_call":
+		input := map[string]interface{}{}
+		if message.ToolArgs != "" {
+			if err := json.Unmarshal([]byte(message.ToolArgs), &input); err != nil {
+				// If parsing fails, wrap in a simple structure
+				input = map[string]interface{}{"arguments": message.ToolArgs}
+			}
+		}
+		return &types.ContentBlockMemberToolUse{Value: types.ToolUseBlock{
+			ToolUseId: aws.String(message.ToolCallID),
+			Name:      aws.String(message.ToolName),
+			Input:     document.NewLazyDocument(input),
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported message type: %s", message.Type)
+	}
+}
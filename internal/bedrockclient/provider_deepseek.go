@@ -0,0 +1,256 @@
+package bedrockclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Ref: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-deepseek.html
+// Ref: https://huggingface.co/deepseek-ai/DeepSeek-R1#usage-recommendations
+
+// deepseekTextGenerationInput is the input of DeepSeek-R1.
+type deepseekTextGenerationInput struct {
+	// The prompt in the R1 chat format. Required
+	Prompt string `json:"prompt"`
+	// Use a lower value to decrease randomness in the response. Optional
+	Temperature float64 `json:"temperature,omitempty"`
+	// Use a lower value to ignore less probable options. Optional
+	TopP float64 `json:"top_p,omitempty"`
+	// The maximum number of tokens to generate. Optional
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// Sequences that stop the generation. Optional
+	Stop []string `json:"stop,omitempty"`
+}
+
+// deepseekTextGenerationOutput is the output of DeepSeek-R1. Streamed
+// chunks have the same form.
+type deepseekTextGenerationOutput struct {
+	Choices []struct {
+		Text string `json:"text"`
+		// One of: ["stop", "length"]
+		StopReason *string `json:"stop_reason"`
+	} `json:"choices"`
+	AmazonBedrockInvocationMetrics *invocationMetrics `json:"amazon-bedrock-invocationMetrics"`
+}
+
+// Special tokens of the R1 chat format.
+const (
+	deepseekBeginOfSentence = "<｜begin▁of▁sentence｜>"
+	deepseekEndOfSentence   = "<｜end▁of▁sentence｜>"
+	deepseekUser            = "<｜User｜>"
+	deepseekAssistant       = "<｜Assistant｜>"
+	deepseekThinkStart      = "<think>"
+	deepseekThinkEnd        = "</think>"
+)
+
+// deepseekProvider serves DeepSeek-R1.
+type deepseekProvider struct{}
+
+func (deepseekProvider) Name() string { return "deepseek" }
+
+func (deepseekProvider) Match(baseModelID string) bool {
+	return strings.HasPrefix(baseModelID, "deepseek.r1")
+}
+
+func (deepseekProvider) BuildRequest(modelID string, messages []Message, options llms.CallOptions) ([]byte, error) {
+	if len(options.Tools) > 0 {
+		return nil, errors.New("tool use is not supported by DeepSeek-R1")
+	}
+	prompt, err := processInputMessagesDeepseek(messages)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&deepseekTextGenerationInput{
+		Prompt:      prompt,
+		Temperature: options.Temperature,
+		TopP:        options.TopP,
+		MaxTokens:   getMaxTokens(options.MaxTokens, 8192),
+		Stop:        options.StopWords,
+	})
+}
+
+func (deepseekProvider) ParseResponse(body []byte) (*llms.ContentResponse, error) {
+	var output deepseekTextGenerationOutput
+	if err := json.Unmarshal(body, &output); err != nil {
+		return nil, err
+	}
+	if len(output.Choices) == 0 {
+		return nil, errors.New("no results")
+	}
+
+	choices := make([]*llms.ContentChoice, len(output.Choices))
+	for i, c := range output.Choices {
+		reasoning, answer := splitDeepseekReasoning(c.Text)
+		choices[i] = &llms.ContentChoice{
+			Content: answer,
+			// Bedrock only reports usage in headers for this model.
+			GenerationInfo: map[string]interface{}{
+				"input_tokens":  0,
+				"output_tokens": 0,
+			},
+		}
+		if c.StopReason != nil {
+			choices[i].StopReason = *c.StopReason
+		}
+		if reasoning != "" {
+			setThinkingBlocks(choices[i], []ThinkingBlock{{Text: reasoning}})
+		}
+	}
+	return &llms.ContentResponse{Choices: choices}, nil
+}
+
+func (deepseekProvider) NewStreamParser(modelID string, options llms.CallOptions) StreamParser {
+	return &deepseekStreamParser{
+		options: options,
+		choice: &llms.ContentChoice{
+			GenerationInfo: map[string]interface{}{
+				"input_tokens":  0,
+				"output_tokens": 0,
+			},
+		},
+		splitter: newDeepseekReasoningSplitter(),
+	}
+}
+
+// processInputMessagesDeepseek formats the messages as an R1 prompt. The
+// prompt ends with an opened think tag, which makes the model reason
+// first. Reasoning of earlier turns is left out, as recommended.
+func processInputMessagesDeepseek(messages []Message) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(deepseekBeginOfSentence)
+	var lastRole ChatMessageType
+	for _, message := range messages {
+		if message.Type != "text" {
+			if message.Type == "tool_call" || message.Type == "tool_result" {
+				return "", errors.New("tool use is not supported by DeepSeek-R1")
+			}
+			continue
+		}
+		role := message.Role
+		if role == ChatMessageTypeGeneric {
+			role = ChatMessageTypeHuman
+		}
+		switch role {
+		case ChatMessageTypeSystem:
+			sb.WriteString(message.Content)
+		case ChatMessageTypeHuman:
+			if lastRole == ChatMessageTypeAI {
+				sb.WriteString(deepseekEndOfSentence)
+			}
+			if lastRole != ChatMessageTypeHuman {
+				sb.WriteString(deepseekUser)
+			}
+			sb.WriteString(message.Content)
+		case ChatMessageTypeAI:
+			if lastRole != ChatMessageTypeAI {
+				sb.WriteString(deepseekAssistant)
+			}
+			sb.WriteString(message.Content)
+		default:
+			return "", errors.New("role not supported")
+		}
+		lastRole = role
+	}
+	if lastRole == ChatMessageTypeAI {
+		sb.WriteString(deepseekEndOfSentence)
+	}
+	sb.WriteString(deepseekAssistant + deepseekThinkStart + "\n")
+	return sb.String(), nil
+}
+
+// newDeepseekReasoningSplitter returns a splitter for an R1 generation.
+// As the prompt opens the think tag, the generation starts with the
+// reasoning, and one without a closing tag was cut off while reasoning.
+func newDeepseekReasoningSplitter() *reasoningSplitter {
+	return &reasoningSplitter{open: deepseekThinkStart, close: deepseekThinkEnd, inside: true}
+}
+
+// splitDeepseekReasoning splits a generation into the reasoning and the
+// answer.
+func splitDeepseekReasoning(text string) (reasoning, answer string) {
+	splitter := newDeepseekReasoningSplitter()
+	reasoning, answer = splitter.split(text)
+	pendingReasoning, pendingAnswer := splitter.flush()
+	reasoning = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(reasoning+pendingReasoning), deepseekThinkStart))
+	return reasoning, strings.TrimSpace(answer + pendingAnswer)
+}
+
+// deepseekStreamParser splits a streamed R1 generation into reasoning and
+// answer deltas.
+type deepseekStreamParser struct {
+	options   llms.CallOptions
+	choice    *llms.ContentChoice
+	reasoning strings.Builder
+	splitter  *reasoningSplitter
+}
+
+func (p *deepseekStreamParser) ParseChunk(ctx context.Context, chunk []byte) error {
+	var resp deepseekTextGenerationOutput
+	if err := json.Unmarshal(chunk, &resp); err != nil {
+		return err
+	}
+	if metrics := resp.AmazonBedrockInvocationMetrics; metrics != nil {
+		if err := metrics.reportUsage(ctx, p.options, p.choice); err != nil {
+			return err
+		}
+	}
+	if len(resp.Choices) == 0 {
+		return nil
+	}
+	if stopReason := resp.Choices[0].StopReason; stopReason != nil {
+		p.choice.StopReason = *stopReason
+	}
+
+	reasoning, answer := p.splitter.split(resp.Choices[0].Text)
+	if err := p.addReasoning(ctx, reasoning); err != nil {
+		return err
+	}
+	return p.addAnswer(ctx, answer)
+}
+
+func (p *deepseekStreamParser) addReasoning(ctx context.Context, text string) error {
+	if p.reasoning.Len() == 0 {
+		text = strings.TrimLeft(strings.TrimPrefix(strings.TrimLeft(text, " \r\n"), deepseekThinkStart), " \r\n")
+	}
+	if text == "" {
+		return nil
+	}
+	if p.options.StreamingReasoningFunc != nil {
+		if err := p.options.StreamingReasoningFunc(ctx, []byte(text), nil); err != nil {
+			return err
+		}
+	}
+	p.reasoning.WriteString(text)
+	return nil
+}
+
+func (p *deepseekStreamParser) addAnswer(ctx context.Context, text string) error {
+	if p.choice.Content == "" {
+		// The answer is separated from the reasoning by blank lines.
+		text = strings.TrimLeft(text, " \r\n")
+	}
+	if text == "" {
+		return nil
+	}
+	if err := p.options.StreamingFunc(ctx, []byte(text)); err != nil {
+		return err
+	}
+	p.choice.Content += text
+	return nil
+}
+
+func (p *deepseekStreamParser) Result() (*llms.ContentResponse, error) {
+	reasoning, answer := p.splitter.flush()
+	p.reasoning.WriteString(reasoning)
+	p.choice.Content = strings.TrimRight(p.choice.Content+answer, " \r\n")
+	if reasoning := strings.TrimSpace(p.reasoning.String()); reasoning != "" {
+		setThinkingBlocks(p.choice, []ThinkingBlock{{Text: reasoning}})
+	}
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{p.choice},
+	}, nil
+}
@@ -2,10 +2,14 @@ package bedrockclient
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/tmc/langchaingo/llms"
 )
 
@@ -44,13 +48,25 @@ const (
 	MetaCompletionReasonLength = "length"
 )
 
-func createMetaCompletion(ctx context.Context,
-	client *bedrockruntime.Client,
-	modelID string,
-	messages []Message,
-	options llms.CallOptions,
-) (*llms.ContentResponse, error) {
-	txt := processInputMessagesGeneric(messages)
+// metaProvider serves the Meta Llama models.
+type metaProvider struct{}
+
+func (metaProvider) Name() string { return "meta" }
+
+func (metaProvider) Match(baseModelID string) bool {
+	return strings.HasPrefix(baseModelID, "meta.")
+}
+
+func (metaProvider) BuildRequest(modelID string, messages []Message, options llms.CallOptions) ([]byte, error) {
+	var txt string
+	if template := getLlamaTemplate(modelID); template != nil {
+		var err error
+		if txt, err = template.render(messages, options); err != nil {
+			return nil, err
+		}
+	} else {
+		txt = processInputMessagesGeneric(messages)
+	}
 
 	input := &metaTextGenerationInput{
 		Prompt:      txt,
@@ -59,40 +75,477 @@ func createMetaCompletion(ctx context.Context,
 		MaxGenLen:   getMaxTokens(options.MaxTokens, 512),
 	}
 
-	body, err := json.Marshal(input)
+	return json.Marshal(input)
+}
+
+// ParseResponse reads no tool calls, as it does not know the tools that
+// were offered. The client parses responses with parseResponse instead.
+func (p metaProvider) ParseResponse(body []byte) (*llms.ContentResponse, error) {
+	return p.parseResponse(body, llms.CallOptions{})
+}
+
+func (metaProvider) parseResponse(body []byte, options llms.CallOptions) (*llms.ContentResponse, error) {
+	var output metaTextGenerationOutput
+
+	err := json.Unmarshal(body, &output)
 	if err != nil {
 		return nil, err
 	}
 
-	modelInput := &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(modelID),
-		Accept:      aws.String("*/*"),
-		ContentType: aws.String("application/json"),
-		Body:        body,
+	choice := &llms.ContentChoice{
+		Content:    output.Generation,
+		StopReason: output.StopReason,
+		GenerationInfo: map[string]interface{}{
+			"input_tokens":  output.PromptTokenCount,
+			"output_tokens": output.GenerationTokenCount,
+		},
 	}
+	if toolCalls, ok := parseLlamaToolCalls(output.Generation, llamaToolNames(options)); ok {
+		choice.Content = ""
+		choice.ToolCalls = toolCalls
+		choice.FuncCall = toolCalls[0].FunctionCall
+	}
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{choice},
+	}, nil
+}
 
-	resp, err := client.InvokeModel(ctx, modelInput)
-	if err != nil {
-		return nil, err
+func (metaProvider) NewStreamParser(modelID string, options llms.CallOptions) StreamParser {
+	return &metaStreamParser{
+		options: options,
+		choice: &llms.ContentChoice{
+			GenerationInfo: map[string]interface{}{
+				"input_tokens":  0,
+				"output_tokens": 0,
+			},
+		},
+		tools: llamaToolNames(options),
 	}
+}
 
-	var output metaTextGenerationOutput
+// llamaToolNames returns the names of the tools the model may call, or nil
+// if it may call none.
+func llamaToolNames(options llms.CallOptions) map[string]bool {
+	if options.ToolChoice == "none" {
+		return nil
+	}
+	var names map[string]bool
+	for _, tool := range options.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		if names == nil {
+			names = make(map[string]bool)
+		}
+		names[tool.Function.Name] = true
+	}
+	return names
+}
 
-	err = json.Unmarshal(resp.Body, &output)
-	if err != nil {
-		return nil, err
+// metaTextGenerationChunk is a single chunk of a streamed response.
+type metaTextGenerationChunk struct {
+	Generation string `json:"generation"`
+	// Only set on the first chunk.
+	PromptTokenCount *int `json:"prompt_token_count"`
+	// The number of tokens generated so far.
+	GenerationTokenCount int `json:"generation_token_count"`
+	// Only set on the last chunk.
+	StopReason *string `json:"stop_reason"`
+
+	AmazonBedrockInvocationMetrics *invocationMetrics `json:"amazon-bedrock-invocationMetrics"`
+}
+
+// metaStreamParser accumulates the chunks of a streamed response into a
+// single choice. When tools are offered, a generation that starts like a
+// JSON tool call is held back until it is complete, as it cannot be told
+// apart from text before.
+type metaStreamParser struct {
+	options llms.CallOptions
+	choice  *llms.ContentChoice
+	tools   map[string]bool
+	// held is the text held back while it may be a tool call.
+	held     strings.Builder
+	streamed bool
+}
+
+func (p *metaStreamParser) ParseChunk(ctx context.Context, chunk []byte) error {
+	var resp metaTextGenerationChunk
+	if err := json.Unmarshal(chunk, &resp); err != nil {
+		return err
+	}
+	if resp.PromptTokenCount != nil {
+		p.choice.GenerationInfo["input_tokens"] = *resp.PromptTokenCount
+	}
+	if resp.GenerationTokenCount > 0 {
+		p.choice.GenerationInfo["output_tokens"] = resp.GenerationTokenCount
+	}
+	if err := p.addText(ctx, resp.Generation); err != nil {
+		return err
+	}
+	if resp.StopReason != nil {
+		p.choice.StopReason = *resp.StopReason
+		if err := p.finish(ctx); err != nil {
+			return err
+		}
+	}
+	if metrics := resp.AmazonBedrockInvocationMetrics; metrics != nil {
+		return metrics.reportUsage(ctx, p.options, p.choice)
+	}
+	return nil
+}
+
+func (p *metaStreamParser) addText(ctx context.Context, text string) error {
+	if !p.streamed && len(p.tools) > 0 {
+		p.held.WriteString(text)
+		if mayBeLlamaToolCall(p.held.String()) {
+			return nil
+		}
+		text = p.held.String()
+		p.held.Reset()
+	}
+	if text == "" {
+		return nil
 	}
+	p.streamed = true
+	if err := p.options.StreamingFunc(ctx, []byte(text)); err != nil {
+		return err
+	}
+	p.choice.Content += text
+	return nil
+}
 
+// finish parses the held back text once the generation is complete.
+func (p *metaStreamParser) finish(ctx context.Context) error {
+	text := p.held.String()
+	p.held.Reset()
+	toolCalls, ok := parseLlamaToolCalls(text, p.tools)
+	if !ok {
+		p.streamed = true
+		return p.addText(ctx, text)
+	}
+	p.choice.ToolCalls = toolCalls
+	p.choice.FuncCall = toolCalls[0].FunctionCall
+	for _, toolCall := range toolCalls {
+		if err := emitStreamEvent(ctx, p.options, StreamEvent{Type: StreamEventToolCallStart, ToolCall: cloneToolCall(toolCall)}); err != nil {
+			return err
+		}
+		if err := emitStreamEvent(ctx, p.options, StreamEvent{Type: StreamEventToolCallDone, ToolCall: cloneToolCall(toolCall)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *metaStreamParser) Result() (*llms.ContentResponse, error) {
+	// A stream that ended without a stop reason still has its text.
+	p.choice.Content += p.held.String()
 	return &llms.ContentResponse{
-		Choices: []*llms.ContentChoice{
-			{
-				Content:    output.Generation,
-				StopReason: output.StopReason,
-				GenerationInfo: map[string]interface{}{
-					"input_tokens":  output.PromptTokenCount,
-					"output_tokens": output.GenerationTokenCount,
-				},
-			},
-		},
+		Choices: []*llms.ContentChoice{p.choice},
 	}, nil
 }
+
+// Ref: https://www.llama.com/docs/model-cards-and-prompt-formats/llama3_1/
+// Ref: https://www.llama.com/docs/model-cards-and-prompt-formats/llama4/
+
+// Roles of a Llama chat turn.
+const (
+	LlamaRoleSystem    = "system"
+	LlamaRoleUser      = "user"
+	LlamaRoleAssistant = "assistant"
+	// LlamaRoleIPython carries tool results back to the model.
+	LlamaRoleIPython = "ipython"
+)
+
+// llamaTemplate is the chat prompt format of a Llama generation.
+type llamaTemplate struct {
+	headerStart string
+	headerEnd   string
+	endOfTurn   string
+	// tools reports whether the model was trained to call tools.
+	tools bool
+}
+
+var (
+	llama3Template = &llamaTemplate{
+		headerStart: "<|start_header_id|>",
+		headerEnd:   "<|end_header_id|>",
+		endOfTurn:   "<|eot_id|>",
+	}
+	llama31Template = &llamaTemplate{
+		headerStart: "<|start_header_id|>",
+		headerEnd:   "<|end_header_id|>",
+		endOfTurn:   "<|eot_id|>",
+		tools:       true,
+	}
+	llama4Template = &llamaTemplate{
+		headerStart: "<|header_start|>",
+		headerEnd:   "<|header_end|>",
+		endOfTurn:   "<|eot|>",
+		tools:       true,
+	}
+)
+
+// getLlamaTemplate returns the prompt format of a Meta model, or nil for
+// Llama 2 models, which use the generic prompt.
+func getLlamaTemplate(modelID string) *llamaTemplate {
+	baseModelID, ok := BaseModelID(modelID)
+	if !ok {
+		// Pinned models of unknown generation get the current format.
+		return llama31Template
+	}
+	name := strings.TrimPrefix(baseModelID, "meta.")
+	switch {
+	case strings.HasPrefix(name, "llama4"):
+		return llama4Template
+	case strings.HasPrefix(name, "llama3-1"), strings.HasPrefix(name, "llama3-2"), strings.HasPrefix(name, "llama3-3"):
+		return llama31Template
+	case strings.HasPrefix(name, "llama3"):
+		return llama3Template
+	case strings.HasPrefix(name, "llama2"):
+		return nil
+	default:
+		return llama31Template
+	}
+}
+
+// llamaToolPrompt instructs the model to answer with the JSON tool call
+// convention that parseLlamaToolCalls reads.
+const llamaToolPrompt = `You have access to the following functions. To call a function, respond with only a JSON object of the form {"name": function name, "parameters": dictionary of argument name and its value}. Do not use variables. When you receive a function result, use it to answer the original question.`
+
+// llamaToolCall is the JSON form of a tool call in Llama prompts.
+type llamaToolCall struct {
+	Name       string                 `json:"name"`
+	Parameters map[string]interface{} `json:"parameters"`
+	// Arguments is read instead of Parameters, which some Llama 3.2
+	// models write.
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+type llamaTurn struct {
+	role    string
+	content strings.Builder
+}
+
+// render formats the messages as a prompt that ends with the header of
+// the assistant turn to generate.
+func (t *llamaTemplate) render(messages []Message, options llms.CallOptions) (string, error) {
+	toolPrompt, err := t.toolPrompt(options)
+	if err != nil {
+		return "", err
+	}
+
+	var system strings.Builder
+	var turns []*llamaTurn
+	for _, message := range messages {
+		role, err := t.role(message.Role)
+		if err != nil {
+			return "", err
+		}
+		if role == LlamaRoleSystem {
+			system.WriteString(message.Content)
+			continue
+		}
+
+		switch message.Type {
+		case "text":
+			last := t.turn(&turns, role, false)
+			last.content.WriteString(message.Content)
+		case "tool_call":
+			args := map[string]interface{}{}
+			if message.ToolArgs != "" {
+				if err := json.Unmarshal([]byte(message.ToolArgs), &args); err != nil {
+					return "", fmt.Errorf("failed to decode tool arguments: %w", err)
+				}
+			}
+			call, err := json.Marshal(llamaToolCall{Name: message.ToolName, Parameters: args})
+			if err != nil {
+				return "", err
+			}
+			last := t.turn(&turns, role, false)
+			if last.content.Len() > 0 {
+				last.content.WriteString("\n")
+			}
+			last.content.Write(call)
+		case "tool_result":
+			// Every result is a turn of its own, in the order of the calls.
+			last := t.turn(&turns, role, true)
+			last.content.WriteString(message.Content)
+		default:
+			return "", fmt.Errorf("unsupported message type: %s", message.Type)
+		}
+	}
+
+	if toolPrompt != "" {
+		if system.Len() > 0 {
+			system.WriteString("\n\n")
+		}
+		system.WriteString(toolPrompt)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<|begin_of_text|>")
+	if system.Len() > 0 {
+		t.writeTurn(&sb, LlamaRoleSystem, system.String())
+	}
+	for _, turn := range turns {
+		t.writeTurn(&sb, turn.role, turn.content.String())
+	}
+	sb.WriteString(t.headerStart + LlamaRoleAssistant + t.headerEnd + "\n\n")
+	return sb.String(), nil
+}
+
+// turn returns the turn the next content of role goes to, opening a new
+// one if the role changes or always is set.
+func (t *llamaTemplate) turn(turns *[]*llamaTurn, role string, always bool) *llamaTurn {
+	if n := len(*turns); n > 0 && !always && (*turns)[n-1].role == role {
+		return (*turns)[n-1]
+	}
+	turn := &llamaTurn{role: role}
+	*turns = append(*turns, turn)
+	return turn
+}
+
+func (t *llamaTemplate) writeTurn(sb *strings.Builder, role, content string) {
+	sb.WriteString(t.headerStart + role + t.headerEnd + "\n\n")
+	sb.WriteString(content)
+	sb.WriteString(t.endOfTurn)
+}
+
+// role maps a message role to the role of its Llama turn.
+func (t *llamaTemplate) role(role ChatMessageType) (string, error) {
+	switch role {
+	case ChatMessageTypeSystem:
+		return LlamaRoleSystem, nil
+	case ChatMessageTypeAI:
+		return LlamaRoleAssistant, nil
+	case ChatMessageTypeGeneric, ChatMessageTypeHuman:
+		return LlamaRoleUser, nil
+	case ChatMessageTypeFunction, ChatMessageTypeTool:
+		if !t.tools {
+			return "", errors.New("tool use is not supported by this Llama model")
+		}
+		return LlamaRoleIPython, nil
+	default:
+		return "", errors.New("role not supported")
+	}
+}
+
+// toolPrompt returns the system prompt section that describes the tools.
+func (t *llamaTemplate) toolPrompt(options llms.CallOptions) (string, error) {
+	if len(options.Tools) == 0 || options.ToolChoice == "none" {
+		return "", nil
+	}
+	if !t.tools {
+		return "", errors.New("tool use is not supported by this Llama model")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(llamaToolPrompt)
+	toolChoice, err := convertToolChoiceToBedrockToolChoice(options.ToolChoice)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert tool choice: %w", err)
+	}
+	if toolChoice != nil {
+		switch toolChoice.Type {
+		case "any":
+			sb.WriteString(" You must call one of the functions.")
+		case "tool":
+			sb.WriteString(" You must call the function " + toolChoice.Name + ".")
+		}
+	}
+	sb.WriteString("\n")
+	for _, tool := range options.Tools {
+		if tool.Type != "function" || tool.Function == nil {
+			return "", fmt.Errorf("only function tools are supported, got: %s", tool.Type)
+		}
+		def, err := json.Marshal(tool)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode tool %s: %w", tool.Function.Name, err)
+		}
+		sb.WriteString("\n")
+		sb.Write(def)
+	}
+	return sb.String(), nil
+}
+
+// parseLlamaToolCalls reads a generation that consists of one or more
+// JSON tool calls, separated by whitespace or semicolons. It reports false
+// for any other text, and for calls of tools that are not in tools, which
+// are rather JSON answers. Llama does not assign call IDs, so they are
+// synthesized.
+func parseLlamaToolCalls(generation string, tools map[string]bool) ([]llms.ToolCall, bool) {
+	text := strings.TrimSpace(generation)
+	text = strings.TrimPrefix(text, "<|python_tag|>")
+	if !strings.HasPrefix(text, "{") && !strings.HasPrefix(text, "[") {
+		return nil, false
+	}
+
+	var calls []llamaToolCall
+	if strings.HasPrefix(text, "[") {
+		if err := json.Unmarshal([]byte(text), &calls); err != nil {
+			return nil, false
+		}
+	} else {
+		dec := json.NewDecoder(strings.NewReader(text))
+		for {
+			var call llamaToolCall
+			if err := dec.Decode(&call); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, false
+			}
+			calls = append(calls, call)
+			// Skip the separator before the next call.
+			rest := strings.TrimLeft(text[dec.InputOffset():], " \t\r\n;")
+			dec = json.NewDecoder(strings.NewReader(rest))
+			text = rest
+		}
+	}
+	if len(calls) == 0 {
+		return nil, false
+	}
+
+	toolCalls := make([]llms.ToolCall, 0, len(calls))
+	for _, call := range calls {
+		if !tools[call.Name] {
+			return nil, false
+		}
+		if call.Parameters == nil {
+			call.Parameters = call.Arguments
+		}
+		if call.Parameters == nil {
+			call.Parameters = map[string]interface{}{}
+		}
+		toolCall, err := convertBedrockToolCallToLLMToolCall(BedrockToolCall{
+			Type:  AnthropicMessageTypeToolUse,
+			ID:    newToolCallID(),
+			Name:  call.Name,
+			Input: call.Parameters,
+		})
+		if err != nil {
+			return nil, false
+		}
+		toolCalls = append(toolCalls, toolCall)
+	}
+	return toolCalls, true
+}
+
+// mayBeLlamaToolCall reports whether text is the start of a generation
+// that parseLlamaToolCalls could read.
+func mayBeLlamaToolCall(text string) bool {
+	text = strings.TrimLeft(text, " \t\r\n")
+	const pythonTag = "<|python_tag|>"
+	if strings.HasPrefix(pythonTag, text) {
+		return true
+	}
+	text = strings.TrimLeft(strings.TrimPrefix(text, pythonTag), " \t\r\n")
+	return text == "" || strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[")
+}
+
+// newToolCallID returns a random ID for a tool call of a model that does
+// not assign IDs itself.
+func newToolCallID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,316 @@
+package bedrockclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Ref: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-mistral.html
+// Mistral models take a chat completions body, see chat_completions.go,
+// except for the first ones, which take an [INST] prompt.
+
+// mistralPromptModels are the base model ID prefixes of the models that
+// only take an [INST] prompt.
+var mistralPromptModels = []string{
+	"mistral.mistral-7b-instruct",
+	"mistral.mixtral-8x7b-instruct",
+	"mistral.mistral-small-2402",
+}
+
+// mistralTextGenerationInput is the input of the Mistral instruct models.
+type mistralTextGenerationInput struct {
+	// The prompt in the [INST] format. Required
+	Prompt string `json:"prompt"`
+	// The maximum number of tokens to generate. Optional
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// Sequences that stop the generation. Optional
+	Stop []string `json:"stop,omitempty"`
+	// Use a lower value to decrease randomness in the response. Optional
+	Temperature float64 `json:"temperature,omitempty"`
+	// Use a lower value to ignore less probable options. Optional
+	TopP float64 `json:"top_p,omitempty"`
+	// Specify the number of token choices the model uses to generate the next token. Optional
+	TopK int `json:"top_k,omitempty"`
+}
+
+// mistralTextGenerationOutput is the output of the Mistral instruct models.
+// Streamed chunks have the same form.
+type mistralTextGenerationOutput struct {
+	Outputs []struct {
+		Text string `json:"text"`
+		// One of: ["stop", "length"]
+		StopReason *string `json:"stop_reason"`
+	} `json:"outputs"`
+	AmazonBedrockInvocationMetrics *invocationMetrics `json:"amazon-bedrock-invocationMetrics"`
+}
+
+// mistralProvider serves the Mistral AI models.
+type mistralProvider struct {
+	// instruct selects the [INST] prompt format of the Mistral 7B and
+	// Mixtral instruct models for every model. The "mistral-instruct"
+	// variant is only used by pinned models.
+	instruct bool
+}
+
+func (p mistralProvider) Name() string {
+	if p.instruct {
+		return "mistral-instruct"
+	}
+	return "mistral"
+}
+
+func (p mistralProvider) Match(baseModelID string) bool {
+	return !p.instruct && strings.HasPrefix(baseModelID, "mistral.")
+}
+
+// isChat reports whether modelID takes the chat completions body instead
+// of an [INST] prompt. Unknown models are assumed to take it.
+func (p mistralProvider) isChat(modelID string) bool {
+	return !p.instruct && !matchBaseModel(modelID, func(baseModelID string) bool {
+		for _, prefix := range mistralPromptModels {
+			if strings.HasPrefix(baseModelID, prefix) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (p mistralProvider) BuildRequest(modelID string, messages []Message, options llms.CallOptions) ([]byte, error) {
+	if p.isChat(modelID) {
+		input, err := newMistralChatInput(messages, options)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(input)
+	}
+
+	if len(options.Tools) > 0 {
+		return nil, errors.New("tool use is not supported by this Mistral model")
+	}
+	prompt, err := processInputMessagesMistral(messages)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&mistralTextGenerationInput{
+		Prompt:      prompt,
+		MaxTokens:   getMaxTokens(options.MaxTokens, 512),
+		Stop:        options.StopWords,
+		Temperature: options.Temperature,
+		TopP:        options.TopP,
+		TopK:        options.TopK,
+	})
+}
+
+func (mistralProvider) ParseResponse(body []byte) (*llms.ContentResponse, error) {
+	var output mistralTextGenerationOutput
+	if err := json.Unmarshal(body, &output); err != nil {
+		return nil, err
+	}
+	// Only instruct model responses have outputs.
+	if output.Outputs == nil {
+		return parseChatCompletionsResponse(body)
+	}
+	if len(output.Outputs) == 0 {
+		return nil, errors.New("no results")
+	}
+
+	choices := make([]*llms.ContentChoice, len(output.Outputs))
+	for i, o := range output.Outputs {
+		choices[i] = &llms.ContentChoice{
+			Content: o.Text,
+			// Bedrock only reports usage in headers for these models.
+			GenerationInfo: map[string]interface{}{
+				"input_tokens":  0,
+				"output_tokens": 0,
+			},
+		}
+		if o.StopReason != nil {
+			choices[i].StopReason = *o.StopReason
+		}
+	}
+	return &llms.ContentResponse{Choices: choices}, nil
+}
+
+func (p mistralProvider) NewStreamParser(modelID string, options llms.CallOptions) StreamParser {
+	if p.isChat(modelID) {
+		return newChatCompletionsStreamParser(options)
+	}
+	return &mistralStreamParser{
+		options: options,
+		choice: &llms.ContentChoice{
+			GenerationInfo: map[string]interface{}{
+				"input_tokens":  0,
+				"output_tokens": 0,
+			},
+		},
+	}
+}
+
+func newMistralChatInput(messages []Message, options llms.CallOptions) (*chatCompletionsInput, error) {
+	input, err := newChatCompletionsInput(messages, options)
+	if err != nil {
+		return nil, err
+	}
+	input.MaxTokens = getMaxTokens(options.MaxTokens, 4096)
+	// Mistral does not take the number of choices.
+	input.N = 0
+
+	// Mistral accepts "auto", "any" and "none". A specific tool is forced
+	// by offering only that tool.
+	switch choice := input.ToolChoice.(type) {
+	case string:
+		if choice == "required" {
+			input.ToolChoice = "any"
+		}
+	case map[string]interface{}:
+		function, _ := choice["function"].(map[string]string)
+		name := function["name"]
+		for _, tool := range input.Tools {
+			if tool.Function.Name == name {
+				input.Tools = []chatCompletionsTool{tool}
+				break
+			}
+		}
+		input.ToolChoice = "any"
+	}
+
+	// Mistral only accepts tool call IDs of nine alphanumeric characters
+	// and wants the tool name on results.
+	names := make(map[string]string)
+	// IDs of the calls without ID, which are matched to the results
+	// without ID in order.
+	var unmatched []string
+	for i := range input.Messages {
+		message := &input.Messages[i]
+		for j := range message.ToolCalls {
+			call := &message.ToolCalls[j]
+			id := mistralToolCallID(call.ID)
+			if call.ID == "" {
+				// Derived from the call alone, so that the ID is the same
+				// in every request of the conversation.
+				id = mistralToolCallID(fmt.Sprintf("%s/%d/%d", call.Function.Name, i, j))
+				unmatched = append(unmatched, id)
+			}
+			names[id] = call.Function.Name
+			call.ID = id
+		}
+		if message.Role == ChatCompletionsRoleTool {
+			if message.ToolCallID == "" && len(unmatched) > 0 {
+				message.ToolCallID, unmatched = unmatched[0], unmatched[1:]
+			} else {
+				message.ToolCallID = mistralToolCallID(message.ToolCallID)
+			}
+			message.Name = names[message.ToolCallID]
+		}
+	}
+	return input, nil
+}
+
+var mistralToolCallIDPattern = regexp.MustCompile(`^[a-zA-Z0-9]{9}$`)
+
+const alphanumeric = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// mistralToolCallID maps a tool call ID to the nine character form Mistral
+// accepts. Valid IDs, such as the ones Mistral assigns, are kept, others
+// are hashed, so that a call and its result always map to the same ID.
+func mistralToolCallID(id string) string {
+	if mistralToolCallIDPattern.MatchString(id) {
+		return id
+	}
+	sum := sha256.Sum256([]byte(id))
+	b := make([]byte, 9)
+	for i := range b {
+		b[i] = alphanumeric[int(sum[i])%len(alphanumeric)]
+	}
+	return string(b)
+}
+
+// processInputMessagesMistral formats the messages as an [INST] prompt.
+// The system prompt is prepended to the first instruction.
+func processInputMessagesMistral(messages []Message) (string, error) {
+	var system string
+	type turn struct {
+		role    ChatMessageType
+		content string
+	}
+	var turns []turn
+	for _, message := range messages {
+		switch message.Role {
+		case ChatMessageTypeSystem:
+			system += message.Content
+			continue
+		case ChatMessageTypeHuman, ChatMessageTypeGeneric:
+			message.Role = ChatMessageTypeHuman
+		case ChatMessageTypeAI:
+		default:
+			return "", errors.New("tool use is not supported by this Mistral model")
+		}
+		if message.Type != "text" {
+			continue
+		}
+		if n := len(turns); n > 0 && turns[n-1].role == message.Role {
+			turns[n-1].content += "\n" + message.Content
+			continue
+		}
+		turns = append(turns, turn{role: message.Role, content: message.Content})
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<s>")
+	for i, t := range turns {
+		if t.role == ChatMessageTypeAI {
+			sb.WriteString(" " + t.content + "</s>")
+			continue
+		}
+		content := t.content
+		if i == 0 && system != "" {
+			content = system + "\n\n" + content
+		}
+		sb.WriteString("[INST] " + content + " [/INST]")
+	}
+	return sb.String(), nil
+}
+
+// mistralStreamParser accumulates the chunks of a streamed instruct model
+// response into a single choice.
+type mistralStreamParser struct {
+	options llms.CallOptions
+	choice  *llms.ContentChoice
+}
+
+func (p *mistralStreamParser) ParseChunk(ctx context.Context, chunk []byte) error {
+	var resp mistralTextGenerationOutput
+	if err := json.Unmarshal(chunk, &resp); err != nil {
+		return err
+	}
+	if len(resp.Outputs) > 0 {
+		output := resp.Outputs[0]
+		if output.Text != "" {
+			if err := p.options.StreamingFunc(ctx, []byte(output.Text)); err != nil {
+				return err
+			}
+			p.choice.Content += output.Text
+		}
+		if output.StopReason != nil {
+			p.choice.StopReason = *output.StopReason
+		}
+	}
+	if metrics := resp.AmazonBedrockInvocationMetrics; metrics != nil {
+		return metrics.reportUsage(ctx, p.options, p.choice)
+	}
+	return nil
+}
+
+func (p *mistralStreamParser) Result() (*llms.ContentResponse, error) {
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{p.choice},
+	}, nil
+}
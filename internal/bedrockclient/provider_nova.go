@@ -4,9 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/tmc/langchaingo/llms"
 )
 
@@ -19,8 +19,11 @@ import (
 // novaBinGenerationInputSource is the source of the content.
 // It is used for sending binary content such as images to the model.
 type novaBinGenerationInputSource struct {
-	// The bytes of the content. Required if type is "image"
+	// The bytes of the content. Required if the content is not read from
+	// S3
 	Bytes []byte `json:"bytes,omitempty"`
+	// The S3 object of the content. Optional
+	S3Location *S3Location `json:"s3Location,omitempty"`
 }
 
 // novaImageInput is the input for the image content.
@@ -32,13 +35,77 @@ type novaImageInput struct {
 	Source novaBinGenerationInputSource `json:"source,omitempty"`
 }
 
+// novaDocumentInput is the input for the document content.
+type novaDocumentInput struct {
+	// The format of the document. Required if type is "document"
+	// One of: ["pdf", "csv", "doc", "docx", "xls", "xlsx", "html", "txt", "md"]
+	Format string `json:"format"`
+	// The name of the document, unique within the request. Required if
+	// type is "document"
+	Name string `json:"name"`
+	// The source of the content. Required if type is "document"
+	Source novaBinGenerationInputSource `json:"source"`
+}
+
+// novaVideoInput is the input for the video content.
+type novaVideoInput struct {
+	// The format of the video. Required if type is "video"
+	// One of: ["mkv", "mov", "mp4", "webm", "flv", "mpeg", "mpg", "wmv", "three_gp"]
+	Format string `json:"format"`
+	// The source of the content. Required if type is "video"
+	Source novaBinGenerationInputSource `json:"source"`
+}
+
+// novaToolUse is a tool call made by the model.
+type novaToolUse struct {
+	// The ID of the tool call. Required
+	ToolUseID string `json:"toolUseId"`
+	// The name of the called tool. Required
+	Name string `json:"name"`
+	// The arguments of the call. Required
+	Input interface{} `json:"input"`
+}
+
+// novaToolResultContent is a single block of a tool result.
+type novaToolResultContent struct {
+	// The text content. Optional
+	Text string `json:"text,omitempty"`
+	// The JSON content. Optional
+	JSON interface{} `json:"json,omitempty"`
+	// The image content. Optional
+	Image *novaImageInput `json:"image,omitempty"`
+	// The document content. Optional
+	Document *novaDocumentInput `json:"document,omitempty"`
+	// The video content. Optional
+	Video *novaVideoInput `json:"video,omitempty"`
+}
+
+// novaToolResult is the result of a tool call, sent in a user message.
+type novaToolResult struct {
+	// The ID of the tool call the result belongs to. Required
+	ToolUseID string `json:"toolUseId"`
+	// The content of the result. Required
+	Content []novaToolResultContent `json:"content"`
+	// The status of the result. Optional
+	// One of: ["success", "error"]
+	Status string `json:"status,omitempty"`
+}
+
 // novaTextGenerationInputContent is the content of a single input message.
-// It can be either a text or an image.
+// It can be either a text, an image, a tool call or a tool result.
 type novaTextGenerationInputContent struct {
 	// The text content. Required if type is "text"
 	Text string `json:"text,omitempty"`
 	// The image content. Required if type is "image"
 	Image *novaImageInput `json:"image,omitempty"`
+	// The document content. Required if type is "document"
+	Document *novaDocumentInput `json:"document,omitempty"`
+	// The video content. Required if type is "video"
+	Video *novaVideoInput `json:"video,omitempty"`
+	// The tool call. Required if type is "tool_call"
+	ToolUse *novaToolUse `json:"toolUse,omitempty"`
+	// The tool result. Required if type is "tool_result"
+	ToolResult *novaToolResult `json:"toolResult,omitempty"`
 }
 
 // novaTextGenerationInputMessage is a single message in the input.
@@ -70,6 +137,41 @@ type novaInferenceConfigInput struct {
 	StopSequences []string `json:"stopSequences,omitempty"`
 }
 
+// novaToolSpec is the definition of a tool.
+type novaToolSpec struct {
+	// The name of the tool. Required
+	Name string `json:"name"`
+	// The description of the tool. Optional
+	Description string `json:"description,omitempty"`
+	// The JSON schema of the tool input. Required
+	InputSchema struct {
+		JSON interface{} `json:"json"`
+	} `json:"inputSchema"`
+}
+
+// novaTool is a single entry of the tool list.
+type novaTool struct {
+	ToolSpec novaToolSpec `json:"toolSpec"`
+}
+
+// novaToolChoice selects how the model uses the tools. Exactly one of the
+// fields is set.
+type novaToolChoice struct {
+	Auto *struct{} `json:"auto,omitempty"`
+	Any  *struct{} `json:"any,omitempty"`
+	Tool *struct {
+		Name string `json:"name"`
+	} `json:"tool,omitempty"`
+}
+
+// novaToolConfig is the tool configuration for the input.
+type novaToolConfig struct {
+	// The tools available to the model. Required
+	Tools []novaTool `json:"tools"`
+	// How the model uses the tools. Optional, default = auto
+	ToolChoice *novaToolChoice `json:"toolChoice,omitempty"`
+}
+
 // novaTextGenerationInput is the input for the text generation for Amazon Nova Models.
 type novaTextGenerationInput struct {
 	// The messages to send to the model. Required
@@ -78,6 +180,8 @@ type novaTextGenerationInput struct {
 	InferenceConfig novaInferenceConfigInput `json:"inferenceConfig"`
 	// The system prompt for the input. Optional
 	System []*novaSystemPrompt `json:"system,omitempty"`
+	// The tool configuration. Optional
+	ToolConfig *novaToolConfig `json:"toolConfig,omitempty"`
 }
 
 // novaTextGenerationOutput is the output for the text generation for Amazon Nova Models.
@@ -85,18 +189,19 @@ type novaTextGenerationOutput struct {
 	Output struct {
 		Message struct {
 			Content []struct {
-				Text string `json:"text"`
+				Text    string       `json:"text"`
+				ToolUse *novaToolUse `json:"toolUse"`
 			} `json:"content"`
 			Role string `json:"role"`
 		} `json:"message"`
 	} `json:"output"`
 	StopReason string `json:"stopReason"`
 	Usage      struct {
-		InputTokens               int  `json:"inputTokens"`
-		OutputTokens              int  `json:"outputTokens"`
-		TotalTokens               int  `json:"totalTokens"`
-		CacheReadInputTokenCount  *int `json:"cacheReadInputTokenCount"`
-		CacheWriteInputTokenCount *int `json:"cacheWriteInputTokenCount"`
+		InputTokens               int `json:"inputTokens"`
+		OutputTokens              int `json:"outputTokens"`
+		TotalTokens               int `json:"totalTokens"`
+		CacheReadInputTokenCount  int `json:"cacheReadInputTokenCount"`
+		CacheWriteInputTokenCount int `json:"cacheWriteInputTokenCount"`
 	} `json:"usage"`
 }
 
@@ -106,6 +211,7 @@ const (
 	NovaCompletionReasonStopSequence    = "stop_sequence"
 	NovaCompletionReasonMaxTokens       = "max_tokens"
 	NovaCompletionReasonContentFiltered = "content_filtered"
+	NovaCompletionReasonToolUse         = "tool_use"
 )
 
 // Role attribute for the anthropic message.
@@ -119,6 +225,12 @@ const (
 const (
 	NovaMessageTypeText  = "text"
 	NovaMessageTypeImage = "image"
+	// Documents and videos use the generic message types.
+	NovaMessageTypeDocument = MessageTypeDocument
+	NovaMessageTypeVideo    = MessageTypeVideo
+	// Tool calls and results use the generic message types.
+	NovaMessageTypeToolCall   = "tool_call"
+	NovaMessageTypeToolResult = "tool_result"
 )
 
 func novaInputToJSON(inputContents []*novaTextGenerationInputMessage, systemPrompt string, options llms.CallOptions) ([]byte, error) {
@@ -132,47 +244,79 @@ func novaInputToJSON(inputContents []*novaTextGenerationInputMessage, systemProm
 		},
 		System: []*novaSystemPrompt{{Text: systemPrompt}},
 	}
+	toolConfig, err := getNovaToolConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	input.ToolConfig = toolConfig
 	return json.Marshal(input)
 }
 
+func getNovaToolConfig(options llms.CallOptions) (*novaToolConfig, error) {
+	if len(options.Tools) == 0 {
+		return nil, nil
+	}
+
+	bedrockTools, err := convertToolsToBedrockTools(options.Tools)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert tools: %w", err)
+	}
+
+	toolConfig := &novaToolConfig{Tools: make([]novaTool, 0, len(bedrockTools))}
+	for _, tool := range bedrockTools {
+		spec := novaToolSpec{
+			Name:        tool.Name,
+			Description: tool.Description,
+		}
+		spec.InputSchema.JSON = tool.InputSchema
+		toolConfig.Tools = append(toolConfig.Tools, novaTool{ToolSpec: spec})
+	}
+
+	toolChoice, err := convertToolChoiceToBedrockToolChoice(options.ToolChoice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert tool choice: %w", err)
+	}
+	if toolChoice != nil {
+		switch toolChoice.Type {
+		case "auto":
+			toolConfig.ToolChoice = &novaToolChoice{Auto: &struct{}{}}
+		case "any":
+			toolConfig.ToolChoice = &novaToolChoice{Any: &struct{}{}}
+		case "tool":
+			toolConfig.ToolChoice = &novaToolChoice{Tool: &struct {
+				Name string `json:"name"`
+			}{Name: toolChoice.Name}}
+		}
+	}
+	return toolConfig, nil
+}
+
 func parseNovaResponseBody(body []byte) (*novaTextGenerationOutput, error) {
 	var output novaTextGenerationOutput
 	err := json.Unmarshal(body, &output)
 	return &output, err
 }
 
-func createNovaCompletion(ctx context.Context,
-	client *bedrockruntime.Client,
-	modelID string,
-	messages []Message,
-	options llms.CallOptions,
-) (*llms.ContentResponse, error) {
-	inputContents, systemPrompt, err := processInputMessagesNova(messages)
-	if err != nil {
-		return nil, err
-	}
+// novaProvider serves the Amazon Nova understanding models.
+type novaProvider struct{}
 
-	body, err := novaInputToJSON(inputContents, systemPrompt, options)
-	if err != nil {
-		return nil, err
-	}
+func (novaProvider) Name() string { return "nova" }
 
-	if options.StreamingFunc != nil {
-		return nil, errors.New("streaming not implemented for nova")
-	}
+func (novaProvider) Match(baseModelID string) bool {
+	return strings.HasPrefix(baseModelID, "amazon.nova-")
+}
 
-	modelInput := &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(modelID),
-		Accept:      aws.String("*/*"),
-		ContentType: aws.String("application/json"),
-		Body:        body,
-	}
-	resp, err := client.InvokeModel(ctx, modelInput)
+func (novaProvider) BuildRequest(modelID string, messages []Message, options llms.CallOptions) ([]byte, error) {
+	inputContents, systemPrompt, err := processInputMessagesNova(messages)
 	if err != nil {
 		return nil, err
 	}
 
-	output, err := parseNovaResponseBody(resp.Body)
+	return novaInputToJSON(inputContents, systemPrompt, options)
+}
+
+func (novaProvider) ParseResponse(body []byte) (*llms.ContentResponse, error) {
+	output, err := parseNovaResponseBody(body)
 	if err != nil {
 		return nil, err
 	}
@@ -183,70 +327,228 @@ func createNovaCompletion(ctx context.Context,
 	} else if stopReason := output.StopReason; stopReason != NovaCompletionReasonEndTurn &&
 		stopReason != NovaCompletionReasonStopSequence &&
 		stopReason != NovaCompletionReasonMaxTokens &&
-		stopReason != NovaCompletionReasonContentFiltered {
+		stopReason != NovaCompletionReasonContentFiltered &&
+		stopReason != NovaCompletionReasonToolUse {
 		return nil, errors.New("completed due to " + stopReason + ". Maybe try increasing max tokens")
 	}
-	Contentchoices := make([]*llms.ContentChoice, len(content))
-	for i, c := range content {
-		Contentchoices[i] = &llms.ContentChoice{
-			Content:    c.Text,
-			StopReason: output.StopReason,
+
+	choice := &llms.ContentChoice{
+		StopReason: output.StopReason,
+		GenerationInfo: map[string]interface{}{
+			"input_tokens":                    output.Usage.InputTokens,
+			"output_tokens":                   output.Usage.OutputTokens,
+			GenerationInfoCacheReadTokens:     output.Usage.CacheReadInputTokenCount,
+			GenerationInfoCacheCreationTokens: output.Usage.CacheWriteInputTokenCount,
+		},
+	}
+	for _, c := range content {
+		choice.Content += c.Text
+		if c.ToolUse == nil {
+			continue
+		}
+		input := c.ToolUse.Input
+		if input == nil {
+			input = map[string]interface{}{}
+		}
+		toolCall, err := convertBedrockToolCallToLLMToolCall(BedrockToolCall{
+			Type:  AnthropicMessageTypeToolUse,
+			ID:    c.ToolUse.ToolUseID,
+			Name:  c.ToolUse.Name,
+			Input: input,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert tool call: %w", err)
+		}
+		choice.ToolCalls = append(choice.ToolCalls, toolCall)
+	}
+	if len(choice.ToolCalls) > 0 {
+		choice.FuncCall = choice.ToolCalls[0].FunctionCall
+	}
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{choice},
+	}, nil
+}
+
+func (novaProvider) NewStreamParser(modelID string, options llms.CallOptions) StreamParser {
+	return &novaStreamParser{
+		options: options,
+		choice: &llms.ContentChoice{
 			GenerationInfo: map[string]interface{}{
-				"input_tokens":  output.Usage.InputTokens,
-				"output_tokens": output.Usage.OutputTokens,
+				"input_tokens":  0,
+				"output_tokens": 0,
+			},
+		},
+		toolIndex: make(map[int]int),
+	}
+}
+
+// novaStreamChunk is a single chunk of a streamed Nova response. Exactly
+// one of the event fields is set.
+type novaStreamChunk struct {
+	MessageStart *struct {
+		Role string `json:"role"`
+	} `json:"messageStart"`
+	ContentBlockStart *struct {
+		ContentBlockIndex int `json:"contentBlockIndex"`
+		Start             struct {
+			ToolUse *struct {
+				ToolUseID string `json:"toolUseId"`
+				Name      string `json:"name"`
+			} `json:"toolUse"`
+		} `json:"start"`
+	} `json:"contentBlockStart"`
+	ContentBlockDelta *struct {
+		ContentBlockIndex int `json:"contentBlockIndex"`
+		Delta             struct {
+			Text    *string `json:"text"`
+			ToolUse *struct {
+				Input string `json:"input"`
+			} `json:"toolUse"`
+		} `json:"delta"`
+	} `json:"contentBlockDelta"`
+	ContentBlockStop *struct {
+		ContentBlockIndex int `json:"contentBlockIndex"`
+	} `json:"contentBlockStop"`
+	MessageStop *struct {
+		StopReason string `json:"stopReason"`
+	} `json:"messageStop"`
+	Metadata *struct {
+		Usage struct {
+			InputTokens               int `json:"inputTokens"`
+			OutputTokens              int `json:"outputTokens"`
+			CacheReadInputTokenCount  int `json:"cacheReadInputTokenCount"`
+			CacheWriteInputTokenCount int `json:"cacheWriteInputTokenCount"`
+		} `json:"usage"`
+	} `json:"metadata"`
+}
+
+// novaStreamParser accumulates the events of a streamed Nova response
+// into a single choice.
+type novaStreamParser struct {
+	options llms.CallOptions
+	choice  *llms.ContentChoice
+	// position of the tool calls in choice.ToolCalls by block index
+	toolIndex map[int]int
+}
+
+func (p *novaStreamParser) ParseChunk(ctx context.Context, chunk []byte) error {
+	var resp novaStreamChunk
+	if err := json.Unmarshal(chunk, &resp); err != nil {
+		return err
+	}
+
+	switch {
+	case resp.ContentBlockStart != nil:
+		toolUse := resp.ContentBlockStart.Start.ToolUse
+		if toolUse == nil {
+			return nil
+		}
+		p.toolIndex[resp.ContentBlockStart.ContentBlockIndex] = len(p.choice.ToolCalls)
+		toolCall := llms.ToolCall{
+			ID:   toolUse.ToolUseID,
+			Type: "function",
+			FunctionCall: &llms.FunctionCall{
+				Name: toolUse.Name,
 			},
 		}
+		p.choice.ToolCalls = append(p.choice.ToolCalls, toolCall)
+		return emitStreamEvent(ctx, p.options, StreamEvent{Type: StreamEventToolCallStart, ToolCall: cloneToolCall(toolCall)})
+	case resp.ContentBlockDelta != nil:
+		delta := resp.ContentBlockDelta.Delta
+		if delta.Text != nil {
+			if err := p.options.StreamingFunc(ctx, []byte(*delta.Text)); err != nil {
+				return err
+			}
+			p.choice.Content += *delta.Text
+		}
+		if delta.ToolUse != nil {
+			i, ok := p.toolIndex[resp.ContentBlockDelta.ContentBlockIndex]
+			if !ok {
+				return errors.New("tool use delta without matching block start")
+			}
+			toolCall := p.choice.ToolCalls[i]
+			toolCall.FunctionCall.Arguments += delta.ToolUse.Input
+			return emitStreamEvent(ctx, p.options, StreamEvent{
+				Type:           StreamEventToolCallDelta,
+				ToolCall:       cloneToolCall(toolCall),
+				ArgumentsDelta: delta.ToolUse.Input,
+			})
+		}
+	case resp.ContentBlockStop != nil:
+		if i, ok := p.toolIndex[resp.ContentBlockStop.ContentBlockIndex]; ok {
+			toolCall := p.choice.ToolCalls[i]
+			// A tool call without arguments streams no deltas at all.
+			if toolCall.FunctionCall.Arguments == "" {
+				toolCall.FunctionCall.Arguments = "{}"
+			}
+			return emitStreamEvent(ctx, p.options, StreamEvent{Type: StreamEventToolCallDone, ToolCall: cloneToolCall(toolCall)})
+		}
+	case resp.MessageStop != nil:
+		p.choice.StopReason = resp.MessageStop.StopReason
+	case resp.Metadata != nil:
+		usage := resp.Metadata.Usage
+		p.choice.GenerationInfo["input_tokens"] = usage.InputTokens
+		p.choice.GenerationInfo["output_tokens"] = usage.OutputTokens
+		p.choice.GenerationInfo[GenerationInfoCacheReadTokens] = usage.CacheReadInputTokenCount
+		p.choice.GenerationInfo[GenerationInfoCacheCreationTokens] = usage.CacheWriteInputTokenCount
+		return emitStreamEvent(ctx, p.options, StreamEvent{Type: StreamEventUsage, Usage: copyUsage(p.choice.GenerationInfo)})
+	}
+	return nil
+}
+
+func (p *novaStreamParser) Result() (*llms.ContentResponse, error) {
+	for _, toolCall := range p.choice.ToolCalls {
+		if toolCall.FunctionCall.Arguments == "" {
+			toolCall.FunctionCall.Arguments = "{}"
+		}
+	}
+	if len(p.choice.ToolCalls) > 0 {
+		p.choice.FuncCall = p.choice.ToolCalls[0].FunctionCall
 	}
 	return &llms.ContentResponse{
-		Choices: Contentchoices,
+		Choices: []*llms.ContentChoice{p.choice},
 	}, nil
 }
 
-// process the input messages to anthropic supported input
+// process the input messages to nova supported input
 // returns the input content and system prompt.
 func processInputMessagesNova(messages []Message) ([]*novaTextGenerationInputMessage, string, error) {
-	chunkedMessages := make([][]Message, 0, len(messages))
-	currentChunk := make([]Message, 0, len(messages))
-	var lastRole ChatMessageType
-	for _, message := range messages {
-		if message.Role != lastRole {
-			if len(currentChunk) > 0 {
-				chunkedMessages = append(chunkedMessages, currentChunk)
-			}
-			currentChunk = make([]Message, 0, len(messages))
-		}
-		currentChunk = append(currentChunk, message)
-		lastRole = message.Role
+	if err := converseDocumentLimits.check(messages); err != nil {
+		return nil, "", err
 	}
-	if len(currentChunk) > 0 {
-		chunkedMessages = append(chunkedMessages, currentChunk)
+	if err := checkVideos(messages); err != nil {
+		return nil, "", err
 	}
+	messages = nameDocuments(messages)
 
+	// Messages are grouped by their Nova role, so that tool results and
+	// user text that follow each other end up in a single user turn.
 	inputContents := make([]*novaTextGenerationInputMessage, 0, len(messages))
 	var systemPrompt string
-	for _, chunk := range chunkedMessages {
-		role, err := getNovaRole(chunk[0].Role)
+	var lastRole string
+	for _, message := range messages {
+		// Reasoning blocks of other models cannot be replayed to Nova.
+		if message.Type == MessageTypeThinking || message.Type == MessageTypeRedactedThinking {
+			continue
+		}
+		role, err := getNovaRole(message.Role)
 		if err != nil {
 			return nil, "", err
 		}
 		if role == NovaSystem {
-			if systemPrompt != "" {
+			if systemPrompt != "" && lastRole != NovaSystem {
 				return nil, "", errors.New("multiple system prompts")
 			}
-			for _, message := range chunk {
-				c := getNovaInputContent(message)
-				systemPrompt += c.Text
-			}
+			systemPrompt += getNovaInputContent(message).Text
+			lastRole = role
 			continue
 		}
-		content := make([]novaTextGenerationInputContent, 0, len(chunk))
-		for _, message := range chunk {
-			content = append(content, getNovaInputContent(message))
+		if role != lastRole || len(inputContents) == 0 {
+			inputContents = append(inputContents, &novaTextGenerationInputMessage{Role: role})
 		}
-		inputContents = append(inputContents, &novaTextGenerationInputMessage{
-			Role:    role,
-			Content: content,
-		})
+		last := inputContents[len(inputContents)-1]
+		last.Content = append(last.Content, getNovaInputContent(message))
+		lastRole = role
 	}
 	return inputContents, systemPrompt, nil
 }
@@ -265,7 +567,7 @@ func getNovaRole(role ChatMessageType) (string, error) {
 	case ChatMessageTypeHuman:
 		return NovaRoleUser, nil
 	case ChatMessageTypeFunction, ChatMessageTypeTool:
-		fallthrough
+		return NovaRoleUser, nil // Tool results are sent as user messages
 	default:
 		return "", errors.New("role not supported")
 	}
@@ -273,18 +575,73 @@ func getNovaRole(role ChatMessageType) (string, error) {
 
 func getNovaInputContent(message Message) novaTextGenerationInputContent {
 	var c novaTextGenerationInputContent
-	if message.Type == NovaMessageTypeText {
+	switch message.Type {
+	case NovaMessageTypeText:
 		c = novaTextGenerationInputContent{
 			Text: message.Content,
 		}
-	} else if message.Type == NovaMessageTypeImage {
+	case NovaMessageTypeImage:
 		c = novaTextGenerationInputContent{}
 		c.Image = &novaImageInput{
 			Format: mimeTypeToFormat(message.MimeType),
 			Source: novaBinGenerationInputSource{
-				Bytes: []byte(message.Content),
+				Bytes:      []byte(message.Content),
+				S3Location: message.S3Location,
 			},
 		}
+	case NovaMessageTypeDocument:
+		c.Document = &novaDocumentInput{
+			Format: DocumentFormat(message.MimeType),
+			Name:   message.Name,
+			Source: novaBinGenerationInputSource{
+				Bytes:      []byte(message.Content),
+				S3Location: message.S3Location,
+			},
+		}
+	case NovaMessageTypeVideo:
+		c.Video = &novaVideoInput{
+			Format: VideoFormat(message.MimeType),
+			Source: novaBinGenerationInputSource{
+				Bytes:      []byte(message.Content),
+				S3Location: message.S3Location,
+			},
+		}
+	case NovaMessageTypeToolCall:
+		var input interface{} = map[string]interface{}{}
+		if message.ToolArgs != "" {
+			var args map[string]interface{}
+			if err := json.Unmarshal([]byte(message.ToolArgs), &args); err == nil {
+				input = args
+			} else {
+				// If parsing fails, wrap in a simple structure
+				input = map[string]interface{}{"arguments": message.ToolArgs}
+			}
+		}
+		c.ToolUse = &novaToolUse{
+			ToolUseID: message.ToolCallID,
+			Name:      message.ToolName,
+			Input:     input,
+		}
+	case NovaMessageTypeToolResult:
+		c.ToolResult = &novaToolResult{
+			ToolUseID: message.ToolUseID,
+			Content:   []novaToolResultContent{{Text: message.Content}},
+		}
+		if message.ToolError {
+			c.ToolResult.Status = "error"
+		}
+		if len(message.Parts) > 0 {
+			c.ToolResult.Content = make([]novaToolResultContent, 0, len(message.Parts))
+			for _, part := range message.Parts {
+				block := getNovaInputContent(part)
+				c.ToolResult.Content = append(c.ToolResult.Content, novaToolResultContent{
+					Text:     block.Text,
+					Image:    block.Image,
+					Document: block.Document,
+					Video:    block.Video,
+				})
+			}
+		}
 	}
 	return c
 }
@@ -0,0 +1,12 @@
+package bedrockclient
+
+// Ref: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-openai.html
+// The gpt-oss models take a chat completions body and return their
+// reasoning enclosed in <reasoning> tags as part of the content.
+var openaiConfig = OpenAICompatibleConfig{
+	Name:                "openai",
+	ModelPrefixes:       []string{"openai."},
+	MaxCompletionTokens: true,
+	Thinking:            ThinkingStyleReasoningEffort,
+	ReasoningTag:        "reasoning",
+}
@@ -0,0 +1,164 @@
+package bedrockclient
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ThinkingStyle selects how a chat completions model is told to think.
+type ThinkingStyle string
+
+const (
+	// ThinkingStyleNone sends no thinking setting; the model decides.
+	ThinkingStyleNone ThinkingStyle = ""
+	// ThinkingStyleReasoningEffort sends "reasoning_effort": "low",
+	// "medium" or "high" when thinking is enabled.
+	ThinkingStyleReasoningEffort ThinkingStyle = "reasoning_effort"
+	// ThinkingStyleEnableThinking sends "enable_thinking": true or false,
+	// and the budget as "thinking_budget".
+	ThinkingStyleEnableThinking ThinkingStyle = "enable_thinking"
+	// ThinkingStyleThinkingType sends "thinking": {"type": "enabled"} or
+	// {"type": "disabled"}.
+	ThinkingStyleThinkingType ThinkingStyle = "thinking"
+)
+
+// OpenAICompatibleConfig describes a model family that takes the OpenAI
+// style chat completions body, see chat_completions.go.
+type OpenAICompatibleConfig struct {
+	// Name is the registry key of the provider. Required
+	Name string
+	// ModelPrefixes are the base model IDs prefixes the provider serves,
+	// e.g. "qwen.". Other model IDs and ARNs can be pinned to Name. Optional
+	ModelPrefixes []string
+	// DefaultMaxTokens applies when the call sets no limit. Optional, default = 4096
+	DefaultMaxTokens int
+	// MaxCompletionTokens sends the limit as "max_completion_tokens"
+	// instead of "max_tokens". Optional
+	MaxCompletionTokens bool
+	// Thinking selects how thinking is toggled. Optional
+	Thinking ThinkingStyle
+	// ReasoningTag is the tag the model encloses reasoning in when it
+	// returns it as part of the content, e.g. "think" for <think>...</think>.
+	// Reasoning in "reasoning_content" or "reasoning" fields is always
+	// read. Optional
+	ReasoningTag string
+	// ExtraBody holds fields added to every request body. Optional
+	ExtraBody map[string]any
+}
+
+// openAICompatibleProvider serves a model family described by an
+// OpenAICompatibleConfig.
+type openAICompatibleProvider struct {
+	config OpenAICompatibleConfig
+}
+
+// NewOpenAICompatibleProvider returns a provider for a model family that
+// takes the chat completions body.
+func NewOpenAICompatibleProvider(config OpenAICompatibleConfig) Provider {
+	return openAICompatibleProvider{config: config}
+}
+
+// Open-weight families on Bedrock that take the chat completions body.
+// Register a provider under the same name to change their settings.
+var openAICompatibleConfigs = []OpenAICompatibleConfig{
+	{Name: "qwen", ModelPrefixes: []string{"qwen."}, Thinking: ThinkingStyleEnableThinking, ReasoningTag: "think"},
+	{Name: "moonshot", ModelPrefixes: []string{"moonshot."}, ReasoningTag: "think"},
+	{Name: "minimax", ModelPrefixes: []string{"minimax."}, ReasoningTag: "think"},
+}
+
+func (p openAICompatibleProvider) Name() string { return p.config.Name }
+
+func (p openAICompatibleProvider) Match(baseModelID string) bool {
+	for _, prefix := range p.config.ModelPrefixes {
+		if strings.HasPrefix(baseModelID, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p openAICompatibleProvider) BuildRequest(modelID string, messages []Message, options llms.CallOptions) ([]byte, error) {
+	input, err := newChatCompletionsInput(messages, options)
+	if err != nil {
+		return nil, err
+	}
+	maxTokens := getMaxTokens(options.MaxTokens, getMaxTokens(p.config.DefaultMaxTokens, 4096))
+	if p.config.MaxCompletionTokens {
+		input.MaxTokens = 0
+		input.MaxCompletionTokens = maxTokens
+	} else {
+		input.MaxTokens = maxTokens
+	}
+
+	enabled, set := getThinkingToggle(options)
+	switch p.config.Thinking {
+	case ThinkingStyleNone:
+	case ThinkingStyleReasoningEffort:
+		input.ReasoningEffort = getReasoningEffort(options)
+	case ThinkingStyleEnableThinking:
+		if set {
+			input.EnableThinking = &enabled
+			input.ThinkingBudget = getThinkingBudget(options)
+		}
+	case ThinkingStyleThinkingType:
+		if set {
+			input.Thinking = &chatCompletionsThinking{Type: "disabled"}
+			if enabled {
+				input.Thinking.Type = "enabled"
+			}
+		}
+	default:
+		return nil, errors.New("unknown thinking style: " + string(p.config.Thinking))
+	}
+
+	body, err := json.Marshal(input)
+	if err != nil || len(p.config.ExtraBody) == 0 {
+		return body, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range p.config.ExtraBody {
+		fields[k] = v
+	}
+	return json.Marshal(fields)
+}
+
+func (p openAICompatibleProvider) ParseResponse(body []byte) (*llms.ContentResponse, error) {
+	resp, err := parseChatCompletionsResponse(body)
+	if err != nil {
+		return nil, err
+	}
+	if p.config.ReasoningTag == "" {
+		return resp, nil
+	}
+	for _, choice := range resp.Choices {
+		splitter := p.newReasoningSplitter()
+		if !strings.Contains(choice.Content, splitter.open) {
+			continue
+		}
+		reasoning, answer := splitter.split(choice.Content)
+		pendingReasoning, pendingAnswer := splitter.flush()
+		choice.Content = strings.TrimSpace(answer + pendingAnswer)
+		if reasoning := strings.TrimSpace(choice.ReasoningContent + reasoning + pendingReasoning); reasoning != "" {
+			setThinkingBlocks(choice, []ThinkingBlock{{Text: reasoning}})
+		}
+	}
+	return resp, nil
+}
+
+func (p openAICompatibleProvider) NewStreamParser(modelID string, options llms.CallOptions) StreamParser {
+	parser := newChatCompletionsStreamParser(options)
+	if p.config.ReasoningTag != "" {
+		parser.splitter = p.newReasoningSplitter()
+	}
+	return parser
+}
+
+func (p openAICompatibleProvider) newReasoningSplitter() *reasoningSplitter {
+	return &reasoningSplitter{open: "<" + p.config.ReasoningTag + ">", close: "</" + p.config.ReasoningTag + ">"}
+}
@@ -0,0 +1,110 @@
+package bedrockclient
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+type fakeProvider struct{ name string }
+
+func (p fakeProvider) Name() string                  { return p.name }
+func (p fakeProvider) Match(baseModelID string) bool { return baseModelID == "acme.model-v1" }
+func (p fakeProvider) BuildRequest(string, []Message, llms.CallOptions) ([]byte, error) {
+	return nil, nil
+}
+func (p fakeProvider) ParseResponse([]byte) (*llms.ContentResponse, error)   { return nil, nil }
+func (p fakeProvider) NewStreamParser(string, llms.CallOptions) StreamParser { return nil }
+
+func TestRegistryResolve(t *testing.T) {
+	r := newDefaultRegistry()
+	r.Register(fakeProvider{name: "acme"})
+	r.Pin("arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/a1b2c3", "anthropic")
+	r.Pin("arn:aws:bedrock:us-east-1:123456789012:provisioned-model/xyz", "missing")
+
+	tests := []struct {
+		modelID string
+		want    string
+		wantErr bool
+	}{
+		{modelID: "anthropic.claude-3-haiku-20240307-v1:0", want: "anthropic"},
+		{modelID: "us.anthropic.claude-sonnet-4-5-20250929-v1:0", want: "anthropic"},
+		{modelID: "global.anthropic.claude-sonnet-4-5-20250929-v1:0", want: "anthropic"},
+		{modelID: "us.amazon.nova-pro-v1:0", want: "nova"},
+		{modelID: "amazon.titan-text-express-v1", want: "amazon"},
+		{modelID: "us.meta.llama3-2-11b-instruct-v1:0", want: "meta"},
+		{modelID: "cohere.command-r-plus-v1:0", want: "cohere"},
+		{modelID: "ai21.jamba-1-5-large-v1:0", want: "ai21"},
+		{modelID: "acme.model-v1", want: "acme"},
+		{modelID: "arn:aws:bedrock:us-east-1::foundation-model/meta.llama3-8b-instruct-v1:0", want: "meta"},
+		{modelID: "arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.amazon.nova-lite-v1:0", want: "nova"},
+		{modelID: "arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/a1b2c3", want: "anthropic"},
+		{modelID: "arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/other", wantErr: true},
+		{modelID: "arn:aws:bedrock:us-east-1:123456789012:provisioned-model/xyz", wantErr: true},
+		// Model IDs merely containing a vendor name must not be misrouted.
+		{modelID: "acme.metamodel-amazon-v1", wantErr: true},
+		{modelID: "amazon.titan-embed-text-v2:0", wantErr: true},
+	}
+	for _, tt := range tests {
+		p, err := r.Resolve(tt.modelID)
+		if tt.wantErr {
+			if !errors.Is(err, ErrUnsupportedProvider) {
+				t.Errorf("Resolve(%q) error = %v, want ErrUnsupportedProvider", tt.modelID, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Resolve(%q) unexpected error: %v", tt.modelID, err)
+			continue
+		}
+		if p.Name() != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.modelID, p.Name(), tt.want)
+		}
+	}
+}
+
+func TestPinnedLegacyFormats(t *testing.T) {
+	r := newDefaultRegistry()
+	messages := []Message{{Role: ChatMessageTypeHuman, Type: "text", Content: "Hi"}}
+	tests := []struct {
+		provider string
+		// field is only in the body of the legacy format.
+		field string
+	}{
+		{"ai21", "messages"},
+		{"ai21-jurassic", "prompt"},
+		{"mistral", "messages"},
+		{"mistral-instruct", "prompt"},
+		{"cohere", "message"},
+		{"cohere-command", "prompt"},
+	}
+	for _, tt := range tests {
+		arn := "arn:aws:bedrock:us-east-1:123456789012:provisioned-model/" + tt.provider
+		r.Pin(arn, tt.provider)
+		p, err := r.Resolve(arn)
+		if err != nil {
+			t.Fatalf("Resolve(%q) error = %v", arn, err)
+		}
+		body, err := p.BuildRequest(arn, messages, llms.CallOptions{})
+		if err != nil {
+			t.Fatalf("%s: BuildRequest() error = %v", tt.provider, err)
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(body, &fields); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := fields[tt.field]; !ok {
+			t.Errorf("%s: body %s has no %q", tt.provider, body, tt.field)
+		}
+	}
+
+	// The variants are never inferred.
+	for _, modelID := range []string{"ai21.j2-ultra-v1", "mistral.mistral-7b-instruct-v0:2", "cohere.command-text-v14"} {
+		if p, err := r.Resolve(modelID); err != nil || strings.Contains(p.Name(), "-") {
+			t.Errorf("Resolve(%q) = %v, %v", modelID, p, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package bedrockclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// S3Location references an object in Amazon S3 instead of inlining it.
+type S3Location struct {
+	// URI is the object URI, e.g. "s3://bucket/key".
+	URI string `json:"uri"`
+	// BucketOwner is the account ID of the bucket owner. Optional
+	BucketOwner string `json:"bucketOwner,omitempty"`
+}
+
+// ObjectGetter reads objects from S3, for models that cannot read them
+// themselves.
+type ObjectGetter interface {
+	GetObject(ctx context.Context, bucket, key string) ([]byte, error)
+}
+
+// ObjectGetterFunc adapts a function to an ObjectGetter.
+type ObjectGetterFunc func(ctx context.Context, bucket, key string) ([]byte, error)
+
+func (f ObjectGetterFunc) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	return f(ctx, bucket, key)
+}
+
+// ParseS3URI splits an "s3://bucket/key" URI into the bucket and the key.
+func ParseS3URI(uri string) (bucket, key string, ok bool) {
+	rest, ok := strings.CutPrefix(uri, "s3://")
+	if !ok {
+		return "", "", false
+	}
+	bucket, key, ok = strings.Cut(rest, "/")
+	if !ok || bucket == "" || key == "" {
+		return "", "", false
+	}
+	return bucket, key, true
+}
+
+// SetObjectGetter sets the getter S3 objects are inlined with for models
+// that cannot read them.
+func (c *Client) SetObjectGetter(getter ObjectGetter) {
+	c.objects = getter
+}
+
+// readsS3 reports whether the models of p take S3 locations. Only Nova
+// reads them, through InvokeModel and Converse alike.
+func readsS3(p Provider) bool {
+	return p != nil && p.Name() == "nova"
+}
+
+// inlineS3Objects returns the messages with the S3 objects they reference
+// fetched into their content. Other messages are kept as they are.
+func (c *Client) inlineS3Objects(ctx context.Context, messages []Message) ([]Message, error) {
+	return replaceMessages(messages, func(message Message) (Message, bool, error) {
+		if message.S3Location == nil {
+			return message, false, nil
+		}
+		if c.objects == nil {
+			return message, false, errors.New("the model cannot read S3 objects and no object getter is set")
+		}
+		bucket, key, ok := ParseS3URI(message.S3Location.URI)
+		if !ok {
+			return message, false, fmt.Errorf("invalid S3 URI: %s", message.S3Location.URI)
+		}
+		data, err := c.objects.GetObject(ctx, bucket, key)
+		if err != nil {
+			return message, false, fmt.Errorf("failed to get %s: %w", message.S3Location.URI, err)
+		}
+		message.Content = string(data)
+		message.S3Location = nil
+		return message, true, nil
+	})
+}
@@ -0,0 +1,95 @@
+package bedrockclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// memoryObjects is an in-memory object store keyed by "bucket/key".
+type memoryObjects map[string][]byte
+
+func (m memoryObjects) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	data, ok := m[bucket+"/"+key]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return data, nil
+}
+
+func TestParseS3URI(t *testing.T) {
+	for uri, want := range map[string][2]string{
+		"s3://media/images/cat.png": {"media", "images/cat.png"},
+		"s3://media/":               {},
+		"s3://media":                {},
+		"https://media/cat.png":     {},
+	} {
+		bucket, key, ok := ParseS3URI(uri)
+		if ok != (want[0] != "") || bucket != want[0] || key != want[1] {
+			t.Errorf("ParseS3URI(%q) = %q, %q, %v", uri, bucket, key, ok)
+		}
+	}
+}
+
+func TestInlineS3Objects(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Describe"},
+		{Role: ChatMessageTypeHuman, Type: "image", MimeType: "image/png", S3Location: &S3Location{URI: "s3://media/cat.png"}},
+	}
+
+	c := &Client{}
+	if _, err := c.inlineS3Objects(context.Background(), messages); err == nil {
+		t.Error("inlined without an object getter")
+	}
+
+	c.SetObjectGetter(memoryObjects{"media/cat.png": []byte("PNG")})
+	inlined, err := c.inlineS3Objects(context.Background(), messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inlined[1].Content != "PNG" || inlined[1].S3Location != nil {
+		t.Errorf("got %+v", inlined[1])
+	}
+	if messages[1].S3Location == nil {
+		t.Error("input messages were modified")
+	}
+
+	messages[1].S3Location.URI = "s3://media/dog.png"
+	if _, err := c.inlineS3Objects(context.Background(), messages); err == nil || !strings.Contains(err.Error(), "NoSuchKey") {
+		t.Errorf("got error %v for a missing object", err)
+	}
+}
+
+func TestS3Requests(t *testing.T) {
+	location := &S3Location{URI: "s3://media/report.pdf", BucketOwner: "111122223333"}
+	messages := []Message{
+		{Role: ChatMessageTypeHuman, Type: MessageTypeDocument, MimeType: "application/pdf", Name: "report.pdf", S3Location: location},
+	}
+
+	t.Run("nova", func(t *testing.T) {
+		body, err := novaProvider{}.BuildRequest("amazon.nova-pro-v1:0", messages, llms.CallOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `"document":{"format":"pdf","name":"report","source":{"s3Location":{"uri":"s3://media/report.pdf","bucketOwner":"111122223333"}}}`
+		if !strings.Contains(string(body), want) {
+			t.Errorf("got body %s", body)
+		}
+	})
+
+	t.Run("converse", func(t *testing.T) {
+		inputMessages, _, err := processInputMessagesConverse(anthropicProvider{}, messages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block := inputMessages[0].Content[0].(*types.ContentBlockMemberDocument)
+		source, ok := block.Value.Source.(*types.DocumentSourceMemberS3Location)
+		if !ok || *source.Value.Uri != location.URI || *source.Value.BucketOwner != location.BucketOwner {
+			t.Errorf("got source %+v", block.Value.Source)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package bedrockclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ErrStreamStopped is returned by a streaming callback to stop the stream
+// early, e.g. because the consumer stopped iterating. The client closes
+// the stream and returns an error wrapping it.
+var ErrStreamStopped = errors.New("stream stopped by consumer")
+
+// StreamError is an error reported inside a response stream, after the
+// call itself was accepted.
+type StreamError struct {
+	// Type is the error type, e.g. "overloaded_error" or
+	// "ModelStreamErrorException".
+	Type string
+	// Message is the error message sent by the service.
+	Message string
+	// Err is the SDK exception the error was read from, if any.
+	Err error
+}
+
+func (e *StreamError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("stream error: %s", e.Type)
+	}
+	return fmt.Sprintf("stream error: %s: %s", e.Type, e.Message)
+}
+
+func (e *StreamError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether an error event sent by the model is transient.
+// SDK exceptions are classified by their own type, see Err.
+func (e *StreamError) Retryable() bool {
+	switch e.Type {
+	case "overloaded_error", "rate_limit_error", "api_error":
+		return true
+	}
+	return false
+}
+
+// eventStream is the part of the SDK event streams the client reads. Both
+// InvokeModelWithResponseStream and ConverseStream streams implement it.
+type eventStream[T any] interface {
+	Events() <-chan T
+	Close() error
+	Err() error
+}
+
+// readStream passes the events of stream to handle until the stream ends,
+// handle fails or ctx is done. The stream is always closed on return,
+// which also stops the goroutine the SDK reads it with.
+func readStream[T any](ctx context.Context, stream eventStream[T], handle func(T) error) error {
+	defer stream.Close()
+
+	events := stream.Events()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-events:
+			if !ok {
+				return newStreamError(stream.Err())
+			}
+			if err := handle(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// apiError is implemented by the SDK exceptions, see smithy.APIError.
+type apiError interface {
+	ErrorCode() string
+	ErrorMessage() string
+}
+
+// newStreamError wraps an exception received in a stream into a
+// *StreamError. Other errors are returned unchanged.
+func newStreamError(err error) error {
+	var streamErr *StreamError
+	if err == nil || errors.As(err, &streamErr) {
+		return err
+	}
+	var apiErr apiError
+	if errors.As(err, &apiErr) {
+		return &StreamError{Type: apiErr.ErrorCode(), Message: apiErr.ErrorMessage(), Err: err}
+	}
+	return err
+}
+
+// invocationMetrics is the trailer Bedrock adds to the last chunk of a
+// streamed InvokeModel response.
+type invocationMetrics struct {
+	InputTokenCount  int `json:"inputTokenCount"`
+	OutputTokenCount int `json:"outputTokenCount"`
+}
+
+// reportUsage stores the token counts of metrics in the choice and emits a
+// usage event.
+func (m *invocationMetrics) reportUsage(ctx context.Context, options llms.CallOptions, choice *llms.ContentChoice) error {
+	choice.GenerationInfo["input_tokens"] = m.InputTokenCount
+	choice.GenerationInfo["output_tokens"] = m.OutputTokenCount
+	return emitStreamEvent(ctx, options, StreamEvent{Type: StreamEventUsage, Usage: copyUsage(choice.GenerationInfo)})
+}
@@ -0,0 +1,71 @@
+package bedrockclient
+
+import (
+	"context"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// StreamEventType identifies a structured streaming event.
+type StreamEventType string
+
+const (
+	// StreamEventToolCallStart is sent when the model starts a tool call.
+	// Only the ID and name of the call are known at this point.
+	StreamEventToolCallStart StreamEventType = "tool_call_start"
+	// StreamEventToolCallDelta is sent for every fragment of tool call arguments.
+	StreamEventToolCallDelta StreamEventType = "tool_call_delta"
+	// StreamEventToolCallDone is sent once the arguments of a tool call are complete.
+	StreamEventToolCallDone StreamEventType = "tool_call_done"
+	// StreamEventUsage is sent whenever the token usage of the response changes.
+	StreamEventUsage StreamEventType = "usage"
+)
+
+// StreamEvent is a streaming event that does not fit the text-only
+// llms.CallOptions.StreamingFunc.
+type StreamEvent struct {
+	Type StreamEventType
+	// ToolCall is the call the event belongs to, with the arguments
+	// received so far. Set for tool call events.
+	ToolCall llms.ToolCall
+	// ArgumentsDelta is the JSON fragment of a StreamEventToolCallDelta.
+	ArgumentsDelta string
+	// Usage holds the token counts in GenerationInfo form. Set for
+	// StreamEventUsage.
+	Usage map[string]interface{}
+}
+
+// StreamEventFunc receives the structured events of a streamed call. It is
+// passed in llms.CallOptions.Metadata under MetadataStreamEventFunc.
+// Returning an error stops the stream like StreamingFunc does.
+type StreamEventFunc func(ctx context.Context, event StreamEvent) error
+
+func emitStreamEvent(ctx context.Context, options llms.CallOptions, event StreamEvent) error {
+	fn, _ := options.Metadata[MetadataStreamEventFunc].(StreamEventFunc)
+	if fn == nil {
+		return nil
+	}
+	return fn(ctx, event)
+}
+
+// cloneToolCall copies a tool call so that later argument deltas do not
+// change an event that was already sent.
+func cloneToolCall(toolCall llms.ToolCall) llms.ToolCall {
+	if toolCall.FunctionCall != nil {
+		fc := *toolCall.FunctionCall
+		toolCall.FunctionCall = &fc
+	}
+	return toolCall
+}
+
+// copyUsage snapshots GenerationInfo token counts so that later updates
+// do not leak into an event that was already sent.
+func copyUsage(info map[string]interface{}) map[string]interface{} {
+	usage := make(map[string]interface{}, 4)
+	for _, key := range []string{"input_tokens", "output_tokens", GenerationInfoCacheReadTokens, GenerationInfoCacheCreationTokens} {
+		if v, ok := info[key]; ok {
+			usage[key] = v
+		}
+	}
+	return usage
+}
@@ -0,0 +1,237 @@
+package bedrockclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// fakeStream mimics the SDK event streams: a reader goroutine delivers the
+// events and, like an open connection, only exits once the stream is
+// closed or fails.
+type fakeStream[T any] struct {
+	events    chan T
+	done      chan struct{}
+	exited    chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+// newFakeStream delivers events, then fails with err if it is not nil, or
+// otherwise keeps the stream open until it is closed.
+func newFakeStream[T any](events []T, err error) *fakeStream[T] {
+	s := &fakeStream[T]{
+		events: make(chan T),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go func() {
+		defer close(s.exited)
+		defer close(s.events)
+		for _, e := range events {
+			select {
+			case s.events <- e:
+			case <-s.done:
+				return
+			}
+		}
+		if err != nil {
+			s.err = err
+			return
+		}
+		<-s.done
+	}()
+	return s
+}
+
+func (s *fakeStream[T]) Events() <-chan T { return s.events }
+
+func (s *fakeStream[T]) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	<-s.exited
+	return nil
+}
+
+func (s *fakeStream[T]) Err() error { return s.err }
+
+// assertClosed fails the test if the reader goroutine of s is still running.
+func assertClosed[T any](t *testing.T, s *fakeStream[T]) {
+	t.Helper()
+	select {
+	case <-s.exited:
+	case <-time.After(time.Second):
+		t.Fatal("stream reader goroutine leaked")
+	}
+}
+
+func anthropicChunks(chunks ...string) []types.ResponseStream {
+	events := make([]types.ResponseStream, len(chunks))
+	for i, chunk := range chunks {
+		events[i] = &types.ResponseStreamMemberChunk{Value: types.PayloadPart{Bytes: []byte(chunk)}}
+	}
+	return events
+}
+
+var anthropicTextChunks = anthropicChunks(
+	`{"type":"message_start","message":{"usage":{"input_tokens":3}}}`,
+	`{"type":"content_block_start","index":0,"content_block":{"type":"text"}}`,
+	`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+	`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}`,
+	`{"type":"content_block_stop","index":0}`,
+	`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+)
+
+func TestReadResponseStreamStopped(t *testing.T) {
+	var chunks int
+	options := llms.CallOptions{
+		StreamingFunc: func(ctx context.Context, chunk []byte) error {
+			chunks++
+			return ErrStreamStopped
+		},
+	}
+	stream := newFakeStream(anthropicTextChunks, nil)
+
+	resp, err := readResponseStream(context.Background(), stream, anthropicProvider{}.NewStreamParser("", options))
+	if !errors.Is(err, ErrStreamStopped) {
+		t.Fatalf("got error %v, want ErrStreamStopped", err)
+	}
+	if resp != nil {
+		t.Errorf("got response %v for a stopped stream", resp)
+	}
+	if chunks != 1 {
+		t.Errorf("streaming func called %d times after stopping", chunks)
+	}
+	assertClosed(t, stream)
+}
+
+func TestReadResponseStreamCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	options := llms.CallOptions{
+		StreamingFunc: func(ctx context.Context, chunk []byte) error {
+			cancel()
+			return nil
+		},
+	}
+	// The stream stays open after the first text chunk.
+	stream := newFakeStream(anthropicTextChunks[:3], nil)
+
+	_, err := readResponseStream(ctx, stream, anthropicProvider{}.NewStreamParser("", options))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	assertClosed(t, stream)
+}
+
+func TestReadResponseStreamErrors(t *testing.T) {
+	options := llms.CallOptions{
+		StreamingFunc: func(ctx context.Context, chunk []byte) error { return nil },
+	}
+
+	t.Run("exception", func(t *testing.T) {
+		stream := newFakeStream(anthropicTextChunks[:3], &types.ModelStreamErrorException{Message: aws.String("boom")})
+		_, err := readResponseStream(context.Background(), stream, anthropicProvider{}.NewStreamParser("", options))
+
+		var streamErr *StreamError
+		if !errors.As(err, &streamErr) {
+			t.Fatalf("got error %v, want *StreamError", err)
+		}
+		if streamErr.Type != "ModelStreamErrorException" || streamErr.Message != "boom" {
+			t.Errorf("got %+v", streamErr)
+		}
+		var exception *types.ModelStreamErrorException
+		if !errors.As(err, &exception) {
+			t.Error("SDK exception is not unwrapped")
+		}
+		assertClosed(t, stream)
+	})
+
+	t.Run("error chunk", func(t *testing.T) {
+		events := append(anthropicTextChunks[:3:3], anthropicChunks(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)...)
+		stream := newFakeStream(events, nil)
+		_, err := readResponseStream(context.Background(), stream, anthropicProvider{}.NewStreamParser("", options))
+
+		var streamErr *StreamError
+		if !errors.As(err, &streamErr) {
+			t.Fatalf("got error %v, want *StreamError", err)
+		}
+		if streamErr.Type != "overloaded_error" || !streamErr.Retryable() {
+			t.Errorf("got %+v, want a retryable overloaded_error", streamErr)
+		}
+		assertClosed(t, stream)
+	})
+}
+
+func TestReadConverseStream(t *testing.T) {
+	events := []types.ConverseStreamOutput{
+		&types.ConverseStreamOutputMemberContentBlockDelta{Value: types.ContentBlockDeltaEvent{
+			ContentBlockIndex: aws.Int32(0),
+			Delta:             &types.ContentBlockDeltaMemberText{Value: "Hello"},
+		}},
+		&types.ConverseStreamOutputMemberContentBlockDelta{Value: types.ContentBlockDeltaEvent{
+			ContentBlockIndex: aws.Int32(0),
+			Delta:             &types.ContentBlockDeltaMemberText{Value: " world"},
+		}},
+		&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: types.StopReasonEndTurn}},
+	}
+
+	t.Run("stopped", func(t *testing.T) {
+		options := llms.CallOptions{
+			StreamingFunc: func(ctx context.Context, chunk []byte) error { return ErrStreamStopped },
+		}
+		stream := newFakeStream(events, nil)
+		_, err := readConverseStream(context.Background(), stream, options)
+		if !errors.Is(err, ErrStreamStopped) {
+			t.Fatalf("got error %v, want ErrStreamStopped", err)
+		}
+		assertClosed(t, stream)
+	})
+
+	t.Run("exception", func(t *testing.T) {
+		options := llms.CallOptions{
+			StreamingFunc: func(ctx context.Context, chunk []byte) error { return nil },
+		}
+		stream := newFakeStream(events[:1], &types.ThrottlingException{Message: aws.String("slow down")})
+		_, err := readConverseStream(context.Background(), stream, options)
+		var streamErr *StreamError
+		if !errors.As(err, &streamErr) || streamErr.Type != "ThrottlingException" {
+			t.Fatalf("got error %v, want a ThrottlingException *StreamError", err)
+		}
+		assertClosed(t, stream)
+	})
+}
+
+func TestAmazonStream(t *testing.T) {
+	var text string
+	options := llms.CallOptions{
+		StreamingFunc: func(ctx context.Context, chunk []byte) error {
+			text += string(chunk)
+			return nil
+		},
+	}
+	parser := amazonProvider{}.NewStreamParser("amazon.titan-text-express-v1", options)
+	for _, chunk := range []string{
+		`{"outputText":"Hello","index":0,"totalOutputTextTokenCount":1,"completionReason":null,"inputTextTokenCount":3}`,
+		`{"outputText":" world","index":0,"totalOutputTextTokenCount":2,"completionReason":"FINISH","inputTextTokenCount":null,"amazon-bedrock-invocationMetrics":{"inputTokenCount":3,"outputTokenCount":2}}`,
+	} {
+		if err := parser.ParseChunk(context.Background(), []byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := parser.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	choice := resp.Choices[0]
+	if text != "Hello world" || choice.Content != text || choice.StopReason != "FINISH" {
+		t.Errorf("streamed %q, got %+v", text, choice)
+	}
+	if choice.GenerationInfo["input_tokens"] != 3 || choice.GenerationInfo["output_tokens"] != 2 {
+		t.Errorf("got usage %v", choice.GenerationInfo)
+	}
+}
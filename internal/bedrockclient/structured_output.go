@@ -0,0 +1,190 @@
+package bedrockclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Claude and Nova have no JSON mode, so structured output is emulated with
+// a synthetic tool whose input schema is the response schema. The model is
+// forced to call it, and its input becomes the text of the response.
+
+// StructuredOutputToolName is the name of the synthetic tool.
+const StructuredOutputToolName = "structured_output"
+
+// structuredOutputValue is the property a response schema that does not
+// describe an object is wrapped in, as tool inputs are always objects.
+const structuredOutputValue = "value"
+
+// ErrStructuredOutput is returned when the response does not match the
+// response schema.
+var ErrStructuredOutput = errors.New("response does not match the response schema")
+
+// structuredOutput is the synthetic tool of a call.
+type structuredOutput struct {
+	// inputSchema is the input schema of the tool.
+	inputSchema map[string]any
+	// wrapped is set if the response schema is wrapped in
+	// structuredOutputValue.
+	wrapped  bool
+	resolved *jsonschema.Resolved
+}
+
+// getStructuredOutput returns the synthetic tool for the response schema
+// of the call, or nil if the call has none.
+func getStructuredOutput(options llms.CallOptions) (*structuredOutput, error) {
+	schema, ok := options.Metadata[MetadataResponseSchema].(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response schema: %w", err)
+	}
+	var jsonSchema jsonschema.Schema
+	if err := json.Unmarshal(raw, &jsonSchema); err != nil {
+		return nil, fmt.Errorf("failed to decode response schema: %w", err)
+	}
+	resolved, err := jsonSchema.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response schema: %w", err)
+	}
+
+	s := &structuredOutput{inputSchema: schema, resolved: resolved}
+	if schema["type"] != "object" {
+		s.wrapped = true
+		s.inputSchema = map[string]any{
+			"type":       "object",
+			"properties": map[string]any{structuredOutputValue: schema},
+			"required":   []string{structuredOutputValue},
+		}
+	}
+	return s, nil
+}
+
+// apply offers the synthetic tool to the model and keeps its calls out of
+// the stream events. Its input is streamed as text instead.
+func (s *structuredOutput) apply(options llms.CallOptions) llms.CallOptions {
+	options.Tools = append(options.Tools[:len(options.Tools):len(options.Tools)], llms.Tool{
+		Type: "function",
+		Function: &llms.FunctionDefinition{
+			Name:        StructuredOutputToolName,
+			Description: "Respond to the user with this tool. Its input is the final response.",
+			Parameters:  s.inputSchema,
+		},
+	})
+	switch {
+	case getThinkingBudget(options) > 0:
+		// Extended thinking does not allow forcing a tool.
+		options.ToolChoice = "auto"
+	case len(options.Tools) > 1:
+		// The model may still need to call the other tools first.
+		options.ToolChoice = "required"
+	default:
+		options.ToolChoice = llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: StructuredOutputToolName}}
+	}
+
+	eventFunc, _ := options.Metadata[MetadataStreamEventFunc].(StreamEventFunc)
+	if eventFunc == nil {
+		return options
+	}
+	streamingFunc := options.StreamingFunc
+	metadata := make(map[string]interface{}, len(options.Metadata))
+	for k, v := range options.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataStreamEventFunc] = StreamEventFunc(func(ctx context.Context, event StreamEvent) error {
+		if event.ToolCall.FunctionCall == nil || event.ToolCall.FunctionCall.Name != StructuredOutputToolName {
+			return eventFunc(ctx, event)
+		}
+		if event.Type != StreamEventToolCallDelta || s.wrapped || streamingFunc == nil {
+			return nil
+		}
+		return streamingFunc(ctx, []byte(event.ArgumentsDelta))
+	})
+	options.Metadata = metadata
+	return options
+}
+
+// unwrap replaces the call of the synthetic tool with its input as text
+// and validates it against the response schema. A response without the
+// call must be JSON text matching the schema, unless it calls other tools.
+func (s *structuredOutput) unwrap(resp *llms.ContentResponse) error {
+	for _, choice := range resp.Choices {
+		var output string
+		found := false
+		toolCalls := choice.ToolCalls[:0]
+		for _, toolCall := range choice.ToolCalls {
+			if toolCall.FunctionCall == nil || toolCall.FunctionCall.Name != StructuredOutputToolName || found {
+				toolCalls = append(toolCalls, toolCall)
+				continue
+			}
+			found = true
+			output = toolCall.FunctionCall.Arguments
+		}
+		choice.ToolCalls = toolCalls
+		choice.FuncCall = nil
+		if len(toolCalls) > 0 {
+			choice.FuncCall = toolCalls[0].FunctionCall
+		}
+
+		if !found {
+			if len(toolCalls) > 0 {
+				continue
+			}
+			output = choice.Content
+		}
+		value, err := s.decode(output, found)
+		if err != nil {
+			return err
+		}
+		if err := s.resolved.Validate(value); err != nil {
+			return fmt.Errorf("%w: %w", ErrStructuredOutput, err)
+		}
+		if found {
+			text, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			choice.Content = string(text)
+			if len(toolCalls) == 0 {
+				choice.StopReason = "end_turn"
+			}
+		}
+	}
+	return nil
+}
+
+// decode reads the response value from the tool input, or from the text
+// of a model that answered without the tool.
+func (s *structuredOutput) decode(output string, toolInput bool) (any, error) {
+	var value any
+	if err := json.Unmarshal([]byte(output), &value); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrStructuredOutput, err)
+	}
+	if !toolInput || !s.wrapped {
+		return value, nil
+	}
+	input, _ := value.(map[string]any)
+	value, ok := input[structuredOutputValue]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %q in tool input", ErrStructuredOutput, structuredOutputValue)
+	}
+	return value, nil
+}
+
+// supportsStructuredOutput reports whether the response schema is
+// emulated for the provider. Other providers return free-form text.
+func supportsStructuredOutput(p Provider) bool {
+	switch p.Name() {
+	case "anthropic", "nova":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package bedrockclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+var weatherSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"city": map[string]any{"type": "string"},
+		"temp": map[string]any{"type": "number"},
+	},
+	"required": []any{"city", "temp"},
+}
+
+func TestStructuredOutputApply(t *testing.T) {
+	var text string
+	var events []StreamEventType
+	options := llms.CallOptions{
+		StreamingFunc: func(ctx context.Context, chunk []byte) error {
+			text += string(chunk)
+			return nil
+		},
+	}
+	SetMetadata(&options, MetadataResponseSchema, weatherSchema)
+	SetMetadata(&options, MetadataStreamEventFunc, StreamEventFunc(func(ctx context.Context, event StreamEvent) error {
+		events = append(events, event.Type)
+		return nil
+	}))
+
+	output, err := getStructuredOutput(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := output.apply(options)
+	if len(applied.Tools) != 1 || applied.Tools[0].Function.Name != StructuredOutputToolName {
+		t.Fatalf("got tools %+v", applied.Tools)
+	}
+	if choice, ok := applied.ToolChoice.(llms.ToolChoice); !ok || choice.Function.Name != StructuredOutputToolName {
+		t.Errorf("got tool choice %v", applied.ToolChoice)
+	}
+
+	eventFunc := applied.Metadata[MetadataStreamEventFunc].(StreamEventFunc)
+	toolCall := llms.ToolCall{ID: "toolu_1", FunctionCall: &llms.FunctionCall{Name: StructuredOutputToolName}}
+	for _, event := range []StreamEvent{
+		{Type: StreamEventToolCallStart, ToolCall: toolCall},
+		{Type: StreamEventToolCallDelta, ToolCall: toolCall, ArgumentsDelta: `{"city":`},
+		{Type: StreamEventToolCallDelta, ToolCall: toolCall, ArgumentsDelta: `"Paris","temp":21}`},
+		{Type: StreamEventToolCallDone, ToolCall: toolCall},
+		{Type: StreamEventUsage, Usage: map[string]any{"output_tokens": 9}},
+	} {
+		if err := eventFunc(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if text != `{"city":"Paris","temp":21}` {
+		t.Errorf("streamed %q", text)
+	}
+	if len(events) != 1 || events[0] != StreamEventUsage {
+		t.Errorf("synthetic tool events were not filtered: %v", events)
+	}
+}
+
+func TestStructuredOutputUnwrap(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  map[string]any
+		choice  *llms.ContentChoice
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "object",
+			schema: weatherSchema,
+			choice: &llms.ContentChoice{
+				StopReason: "tool_use",
+				ToolCalls:  []llms.ToolCall{{ID: "toolu_1", FunctionCall: &llms.FunctionCall{Name: StructuredOutputToolName, Arguments: `{"city":"Paris","temp":21}`}}},
+			},
+			want: `{"city":"Paris","temp":21}`,
+		},
+		{
+			name:   "wrapped array",
+			schema: map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			choice: &llms.ContentChoice{
+				ToolCalls: []llms.ToolCall{{ID: "toolu_1", FunctionCall: &llms.FunctionCall{Name: StructuredOutputToolName, Arguments: `{"value":["a","b"]}`}}},
+			},
+			want: `["a","b"]`,
+		},
+		{
+			name:   "text fallback",
+			schema: weatherSchema,
+			choice: &llms.ContentChoice{Content: `{"city":"Paris","temp":21}`},
+			want:   `{"city":"Paris","temp":21}`,
+		},
+		{
+			name:   "invalid",
+			schema: weatherSchema,
+			choice: &llms.ContentChoice{
+				ToolCalls: []llms.ToolCall{{ID: "toolu_1", FunctionCall: &llms.FunctionCall{Name: StructuredOutputToolName, Arguments: `{"city":"Paris"}`}}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := llms.CallOptions{Metadata: map[string]interface{}{MetadataResponseSchema: test.schema}}
+			output, err := getStructuredOutput(options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = output.unwrap(&llms.ContentResponse{Choices: []*llms.ContentChoice{test.choice}})
+			if test.wantErr {
+				if !errors.Is(err, ErrStructuredOutput) {
+					t.Errorf("got error %v, want ErrStructuredOutput", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.choice.Content != test.want || len(test.choice.ToolCalls) != 0 || test.choice.FuncCall != nil {
+				t.Errorf("got %+v, want content %s", test.choice, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package bedrockclient
+
+import (
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Type attribute for reasoning messages. They are replayed verbatim to the
+// model that produced them, which Bedrock requires for tool-use turns
+// when extended thinking is enabled.
+const (
+	MessageTypeThinking         = "thinking"
+	MessageTypeRedactedThinking = "redacted_thinking"
+)
+
+// GenerationInfoThinkingBlocks is the llms.ContentChoice.GenerationInfo key
+// that holds the []ThinkingBlock of a response, in output order.
+const GenerationInfoThinkingBlocks = "thinking_blocks"
+
+// ThinkingBlock is a reasoning block returned by the model.
+type ThinkingBlock struct {
+	// Text is the reasoning text. Empty for redacted blocks.
+	Text string
+	// Signature verifies Text and must be sent back unmodified.
+	Signature string
+	// RedactedData is the encrypted content of a redacted block.
+	RedactedData string
+}
+
+// Redacted reports whether the block holds encrypted reasoning only.
+func (b ThinkingBlock) Redacted() bool {
+	return b.RedactedData != ""
+}
+
+// MinThinkingBudget is the smallest thinking budget Claude accepts.
+const MinThinkingBudget = 1024
+
+func getThinkingBudget(options llms.CallOptions) int {
+	budget, _ := options.Metadata[MetadataThinkingBudget].(int)
+	if budget <= 0 {
+		return 0
+	}
+	if budget < MinThinkingBudget {
+		return MinThinkingBudget
+	}
+	return budget
+}
+
+// getThinkingToggle reports whether the call enables or disables thinking.
+// set is false if the call leaves it to the model.
+func getThinkingToggle(options llms.CallOptions) (enabled, set bool) {
+	if effort, _ := options.Metadata[MetadataReasoningEffort].(string); effort != "" {
+		return true, true
+	}
+	budget, set := options.Metadata[MetadataThinkingBudget].(int)
+	return budget > 0, set
+}
+
+// Reasoning efforts of models that take one instead of a thinking budget.
+const (
+	ReasoningEffortLow    = "low"
+	ReasoningEffortMedium = "medium"
+	ReasoningEffortHigh   = "high"
+)
+
+// getReasoningEffort returns the reasoning effort of the call. Without an
+// explicit effort it is derived from the thinking budget, if any.
+func getReasoningEffort(options llms.CallOptions) string {
+	if effort, _ := options.Metadata[MetadataReasoningEffort].(string); effort != "" {
+		return effort
+	}
+	switch budget := getThinkingBudget(options); {
+	case budget == 0:
+		return ""
+	case budget <= MinThinkingBudget:
+		return ReasoningEffortLow
+	case budget <= 8192:
+		return ReasoningEffortMedium
+	default:
+		return ReasoningEffortHigh
+	}
+}
+
+// setThinkingBlocks stores the reasoning blocks of a choice and mirrors
+// their text into ReasoningContent.
+func setThinkingBlocks(choice *llms.ContentChoice, blocks []ThinkingBlock) {
+	if len(blocks) == 0 {
+		return
+	}
+	if choice.GenerationInfo == nil {
+		choice.GenerationInfo = map[string]interface{}{}
+	}
+	choice.GenerationInfo[GenerationInfoThinkingBlocks] = blocks
+	choice.ReasoningContent = ""
+	for _, b := range blocks {
+		choice.ReasoningContent += b.Text
+	}
+}
+
+// reasoningSplitter separates reasoning enclosed in tags, such as
+// <think>...</think>, from the answer in generated text. Text is passed in
+// as it streams in; a tag split across deltas is held back until it is
+// complete.
+type reasoningSplitter struct {
+	open, close string
+	// inside is set while the text is reasoning.
+	inside  bool
+	pending string
+}
+
+// split returns the reasoning and answer text that text completes.
+func (s *reasoningSplitter) split(text string) (reasoning, answer string) {
+	text = s.pending + text
+	s.pending = ""
+	for text != "" {
+		tag := s.open
+		if s.inside {
+			tag = s.close
+		}
+		before, after, found := strings.Cut(text, tag)
+		if !found {
+			keep := partialSuffix(text, tag)
+			s.pending = text[len(text)-keep:]
+			before, after = text[:len(text)-keep], ""
+		}
+		if s.inside {
+			reasoning += before
+		} else {
+			answer += before
+		}
+		if found {
+			s.inside = !s.inside
+		}
+		text = after
+	}
+	return reasoning, answer
+}
+
+// flush returns the text held back at the end of the generation.
+func (s *reasoningSplitter) flush() (reasoning, answer string) {
+	text := s.pending
+	s.pending = ""
+	if s.inside {
+		return text, ""
+	}
+	return "", text
+}
+
+// partialSuffix returns the length of the longest suffix of s that is a
+// proper prefix of tag.
+func partialSuffix(s, tag string) int {
+	for n := min(len(s), len(tag)-1); n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
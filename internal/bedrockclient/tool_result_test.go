@@ -0,0 +1,105 @@
+package bedrockclient
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestToolResultParts(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "Take a screenshot"},
+		{Role: ChatMessageTypeAI, Type: "tool_call", ToolCallID: "tooluse_1", ToolName: "screenshot", ToolArgs: "{}"},
+		{Role: ChatMessageTypeFunction, Type: "tool_result", ToolUseID: "tooluse_1", Content: "Done", Parts: []Message{
+			{Role: ChatMessageTypeFunction, Type: "text", Content: "Done"},
+			{Role: ChatMessageTypeFunction, Type: "image", MimeType: "image/png", Content: "PNG"},
+		}},
+	}
+
+	t.Run("anthropic", func(t *testing.T) {
+		body, err := anthropicProvider{}.BuildRequest("anthropic.claude-3-5-sonnet-20241022-v2:0", messages, llms.CallOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `{"type":"tool_result","tool_use_id":"tooluse_1","content":[{"type":"text","text":"Done"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"UE5H"}}]}`
+		if !strings.Contains(string(body), want) {
+			t.Errorf("got body %s", body)
+		}
+	})
+
+	t.Run("nova", func(t *testing.T) {
+		body, err := novaProvider{}.BuildRequest("amazon.nova-pro-v1:0", messages, llms.CallOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `"content":[{"text":"Done"},{"image":{"format":"png","source":{"bytes":"UE5H"}}}]`
+		if !strings.Contains(string(body), want) {
+			t.Errorf("got body %s", body)
+		}
+	})
+
+	t.Run("converse", func(t *testing.T) {
+		inputMessages, _, err := processInputMessagesConverse(anthropicProvider{}, messages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := inputMessages[2].Content[0].(*types.ContentBlockMemberToolResult)
+		if len(result.Value.Content) != 2 {
+			t.Fatalf("got content %#v", result.Value.Content)
+		}
+		if _, ok := result.Value.Content[1].(*types.ToolResultContentBlockMemberImage); !ok {
+			t.Errorf("got block %T", result.Value.Content[1])
+		}
+	})
+
+	t.Run("S3 part", func(t *testing.T) {
+		c := &Client{objects: memoryObjects{"media/chart.png": []byte("PNG")}}
+		withS3 := append([]Message(nil), messages...)
+		withS3[2].Parts = []Message{{Type: "image", MimeType: "image/png", S3Location: &S3Location{URI: "s3://media/chart.png"}}}
+		inlined, err := c.inlineS3Objects(context.Background(), withS3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part := inlined[2].Parts[0]; part.Content != "PNG" || part.S3Location != nil {
+			t.Errorf("got part %+v", part)
+		}
+		if withS3[2].Parts[0].S3Location == nil {
+			t.Error("input messages were modified")
+		}
+	})
+}
+
+func TestToolResultError(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeAI, Type: "tool_call", ToolCallID: "tooluse_1", ToolName: "weather", ToolArgs: "{}"},
+		{Role: ChatMessageTypeFunction, Type: "tool_result", ToolUseID: "tooluse_1", Content: "city not found", ToolError: true},
+	}
+
+	body, err := anthropicProvider{}.BuildRequest("anthropic.claude-3-5-sonnet-20241022-v2:0", messages, llms.CallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"content":"city not found","is_error":true`; !strings.Contains(string(body), want) {
+		t.Errorf("got Claude body %s", body)
+	}
+
+	body, err = novaProvider{}.BuildRequest("amazon.nova-pro-v1:0", messages, llms.CallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"content":[{"text":"city not found"}],"status":"error"`; !strings.Contains(string(body), want) {
+		t.Errorf("got Nova body %s", body)
+	}
+
+	inputMessages, _, err := processInputMessagesConverse(anthropicProvider{}, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := inputMessages[1].Content[0].(*types.ContentBlockMemberToolResult)
+	if result.Value.Status != types.ToolResultStatusError {
+		t.Errorf("got Converse status %q", result.Value.Status)
+	}
+}
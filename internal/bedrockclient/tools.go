@@ -12,6 +12,8 @@ type BedrockTool struct {
 	Name        string      `json:"name"`
 	Description string      `json:"description,omitempty"`
 	InputSchema interface{} `json:"input_schema"`
+	// CacheControl marks the end of a cached prompt prefix. Optional
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
 }
 
 // BedrockToolChoice represents tool choice for Bedrock API
@@ -66,6 +68,11 @@ func convertToolChoiceToBedrockToolChoice(toolChoice interface{}) (*BedrockToolC
 		default:
 			return nil, fmt.Errorf("unsupported tool choice string: %s", choice)
 		}
+	case llms.ToolChoice:
+		if choice.Type == "function" && choice.Function != nil && choice.Function.Name != "" {
+			return &BedrockToolChoice{Type: "tool", Name: choice.Function.Name}, nil
+		}
+		return nil, fmt.Errorf("unsupported tool choice structure")
 	case map[string]interface{}:
 		// Handle structured tool choice like {"type": "tool", "function": {"name": "get_weather"}}
 		if typeVal, ok := choice["type"].(string); ok && typeVal == "function" {
@@ -81,6 +88,19 @@ func convertToolChoiceToBedrockToolChoice(toolChoice interface{}) (*BedrockToolC
 	}
 }
 
+// checkToolChoiceAuto returns an error if the tool choice forces a tool
+// call, which models that always pick tools on their own cannot honour.
+func checkToolChoiceAuto(family string, toolChoice interface{}) error {
+	choice, err := convertToolChoiceToBedrockToolChoice(toolChoice)
+	if err != nil {
+		return fmt.Errorf("failed to convert tool choice: %w", err)
+	}
+	if choice != nil && choice.Type != "auto" {
+		return fmt.Errorf("%s models pick tools on their own and cannot be made to call one", family)
+	}
+	return nil
+}
+
 // convertBedrockToolCallToLLMToolCall converts Bedrock tool call to llms.ToolCall
 func convertBedrockToolCallToLLMToolCall(bedrockCall BedrockToolCall) (llms.ToolCall, error) {
 	// Convert input to JSON string for Arguments field
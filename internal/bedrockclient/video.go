@@ -0,0 +1,95 @@
+package bedrockclient
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MessageTypeVideo is the type of a message that carries a video. Content
+// holds the raw video, or S3Location references it.
+const MessageTypeVideo = "video"
+
+// Video formats of Nova, keyed by MIME type.
+var videoFormats = map[string]string{
+	"video/x-matroska": "mkv",
+	"video/quicktime":  "mov",
+	"video/mp4":        "mp4",
+	"video/webm":       "webm",
+	"video/x-flv":      "flv",
+	"video/mpeg":       "mpeg",
+	"video/mpg":        "mpg",
+	"video/x-ms-wmv":   "wmv",
+	"video/3gpp":       "three_gp",
+}
+
+// VideoFormat returns the video format of a MIME type, e.g. "mp4" for
+// "video/mp4", or "" if the type is not a supported video.
+func VideoFormat(mimeType string) string {
+	return videoFormats[mimeType]
+}
+
+// UnsupportedInputError is returned when a message carries a kind of
+// input the target model does not accept, such as a video for Claude.
+type UnsupportedInputError struct {
+	// ModelID is the model the request was for.
+	ModelID string
+	// Type is the message type, e.g. "video".
+	Type string
+}
+
+func (e *UnsupportedInputError) Error() string {
+	return fmt.Sprintf("model %s does not accept %s input", e.ModelID, e.Type)
+}
+
+// Ref: https://docs.aws.amazon.com/nova/latest/userguide/modalities-video.html
+const (
+	// maxVideos is the number of videos Nova accepts per request.
+	maxVideos = 1
+	// maxInlineVideoBytes is the size of a video Nova accepts inline.
+	// Larger videos, of up to 1 GB, must be read from S3.
+	maxInlineVideoBytes = 25 << 20
+)
+
+// checkVideos returns an error if the videos of messages exceed the limits
+// of Nova.
+func checkVideos(messages []Message) error {
+	var count int
+	for _, message := range flattenMessages(messages) {
+		if message.Type != MessageTypeVideo {
+			continue
+		}
+		count++
+		if VideoFormat(message.MimeType) == "" {
+			return fmt.Errorf("unsupported video type: %s", message.MimeType)
+		}
+		if message.S3Location == nil && len(message.Content) > maxInlineVideoBytes {
+			return fmt.Errorf("video is %d bytes, at most %d are accepted inline, read larger ones from S3", len(message.Content), maxInlineVideoBytes)
+		}
+	}
+	if count > maxVideos {
+		return fmt.Errorf("%d videos attached, the model accepts at most %d", count, maxVideos)
+	}
+	return nil
+}
+
+// checkVideoSupport returns an *UnsupportedInputError if messages carry a
+// video and the model served by p does not accept video. Only Nova Lite,
+// Pro and Premier do.
+func checkVideoSupport(p Provider, modelID string, messages []Message) error {
+	hasVideo := false
+	for _, message := range flattenMessages(messages) {
+		hasVideo = hasVideo || message.Type == MessageTypeVideo
+	}
+	if !hasVideo || p != nil && p.Name() == "nova" && novaSupportsVideo(modelID) {
+		return nil
+	}
+	return &UnsupportedInputError{ModelID: modelID, Type: MessageTypeVideo}
+}
+
+// novaSupportsVideo reports whether modelID is not Nova Micro. A pinned
+// Nova Micro is not told apart, Bedrock rejects its videos instead.
+func novaSupportsVideo(modelID string) bool {
+	return !matchBaseModel(modelID, func(baseModelID string) bool {
+		return strings.HasPrefix(baseModelID, "amazon.nova-micro")
+	})
+}
@@ -0,0 +1,79 @@
+package bedrockclient
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestVideoSupport(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeHuman, Type: MessageTypeVideo, MimeType: "video/mp4", Content: "mp4"},
+	}
+	for _, tc := range []struct {
+		provider Provider
+		modelID  string
+		ok       bool
+	}{
+		{novaProvider{}, "us.amazon.nova-lite-v1:0", true},
+		{novaProvider{}, "amazon.nova-premier-v1:0", true},
+		{novaProvider{}, "amazon.nova-micro-v1:0", false},
+		{anthropicProvider{}, "anthropic.claude-3-5-sonnet-20241022-v2:0", false},
+	} {
+		err := checkVideoSupport(tc.provider, tc.modelID, messages)
+		var inputErr *UnsupportedInputError
+		if tc.ok && err != nil || !tc.ok && (!errors.As(err, &inputErr) || inputErr.Type != MessageTypeVideo) {
+			t.Errorf("%s: got error %v", tc.modelID, err)
+		}
+	}
+	if err := checkVideoSupport(anthropicProvider{}, "anthropic.claude-3-haiku-20240307-v1:0", messages[:0]); err != nil {
+		t.Errorf("got error %v without videos", err)
+	}
+}
+
+func TestVideoLimits(t *testing.T) {
+	video := Message{Role: ChatMessageTypeHuman, Type: MessageTypeVideo, MimeType: "video/webm"}
+	if err := checkVideos([]Message{video, video}); err == nil {
+		t.Error("two videos accepted")
+	}
+	large := video
+	large.Content = strings.Repeat("a", maxInlineVideoBytes+1)
+	if err := checkVideos([]Message{large}); err == nil {
+		t.Error("oversized inline video accepted")
+	}
+	large.Content = ""
+	large.S3Location = &S3Location{URI: "s3://media/large.webm"}
+	if err := checkVideos([]Message{large}); err != nil {
+		t.Errorf("got error %v for an S3 video", err)
+	}
+}
+
+func TestVideoRequests(t *testing.T) {
+	messages := []Message{
+		{Role: ChatMessageTypeHuman, Type: MessageTypeVideo, MimeType: "video/quicktime", S3Location: &S3Location{URI: "s3://media/clip.mov"}},
+		{Role: ChatMessageTypeHuman, Type: "text", Content: "What happens?"},
+	}
+
+	body, err := novaProvider{}.BuildRequest("amazon.nova-pro-v1:0", messages, llms.CallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"video":{"format":"mov","source":{"s3Location":{"uri":"s3://media/clip.mov"}}}`; !strings.Contains(string(body), want) {
+		t.Errorf("got body %s", body)
+	}
+
+	inputMessages, _, err := processInputMessagesConverse(novaProvider{}, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, ok := inputMessages[0].Content[0].(*types.ContentBlockMemberVideo)
+	if !ok || block.Value.Format != types.VideoFormatMov {
+		t.Fatalf("got block %#v", inputMessages[0].Content[0])
+	}
+	if _, ok := block.Value.Source.(*types.VideoSourceMemberS3Location); !ok {
+		t.Errorf("got source %T", block.Value.Source)
+	}
+}
@@ -0,0 +1,258 @@
+package converters
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"github.com/dingdinglz/adk-go-bedrock/internal/bedrockclient"
+	xdraw "golang.org/x/image/draw"
+	"google.golang.org/genai"
+
+	// Decoders for the formats that are converted. GIF animations are
+	// reduced to their first frame.
+	_ "image/gif"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// omittedImageText replaces the images dropped to stay within the image
+// count of a model.
+const omittedImageText = "[image omitted]"
+
+// NormalizeImages returns the contents with their inline images made
+// acceptable to a model with the given limits. Images in other formats,
+// such as BMP or TIFF, are converted to PNG or JPEG, and images above the
+// pixel or byte limits are downsized. The oldest images beyond the image
+// count are replaced with a placeholder text, or dropped from the function
+// responses they were returned in. Images that already fit are kept as
+// they are, and contents are copied rather than modified. Image URLs are
+// not normalized, including those inlined by a URLFetcher.
+func NormalizeImages(contents []*genai.Content, limits bedrockclient.ImageLimits) ([]*genai.Content, error) {
+	var count int
+	for _, content := range contents {
+		if content == nil {
+			continue
+		}
+		for _, part := range content.Parts {
+			count += countImages(part)
+		}
+	}
+	if count == 0 {
+		return contents, nil
+	}
+
+	// The first images are dropped, the model sees the latest ones.
+	drop := 0
+	if limits.MaxCount > 0 && count > limits.MaxCount {
+		drop = count - limits.MaxCount
+	}
+
+	result := make([]*genai.Content, len(contents))
+	for i, content := range contents {
+		result[i] = content
+		if content == nil {
+			continue
+		}
+		var parts []*genai.Part
+		for j, part := range content.Parts {
+			normalized, err := normalizePart(part, limits, &drop)
+			if err != nil {
+				return nil, err
+			}
+			if normalized == part {
+				continue
+			}
+			if parts == nil {
+				parts = append([]*genai.Part(nil), content.Parts...)
+			}
+			parts[j] = normalized
+		}
+		if parts != nil {
+			copied := *content
+			copied.Parts = parts
+			result[i] = &copied
+		}
+	}
+	return result, nil
+}
+
+// countImages returns the number of inline images of part, including the
+// images of a function response.
+func countImages(part *genai.Part) int {
+	if isInlineImage(part) {
+		return 1
+	}
+	var count int
+	if part != nil && part.FunctionResponse != nil {
+		for _, responsePart := range part.FunctionResponse.Parts {
+			if isFunctionResponseImage(responsePart) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// normalizePart returns part with its images normalized, or part itself if
+// they all fit. drop is the number of images still to be dropped.
+func normalizePart(part *genai.Part, limits bedrockclient.ImageLimits, drop *int) (*genai.Part, error) {
+	if isInlineImage(part) {
+		if *drop > 0 {
+			*drop--
+			return genai.NewPartFromText(omittedImageText), nil
+		}
+		blob, err := normalizeImage(part.InlineData, limits)
+		if err != nil || blob == part.InlineData {
+			return part, err
+		}
+		normalized := *part
+		normalized.InlineData = blob
+		return &normalized, nil
+	}
+	if part == nil || part.FunctionResponse == nil || countImages(part) == 0 {
+		return part, nil
+	}
+
+	changed := false
+	responseParts := make([]*genai.FunctionResponsePart, 0, len(part.FunctionResponse.Parts))
+	for _, responsePart := range part.FunctionResponse.Parts {
+		if !isFunctionResponseImage(responsePart) {
+			responseParts = append(responseParts, responsePart)
+			continue
+		}
+		if *drop > 0 {
+			// A function response part cannot hold the placeholder text.
+			*drop--
+			changed = true
+			continue
+		}
+		inline := &genai.Blob{
+			MIMEType:    responsePart.InlineData.MIMEType,
+			Data:        responsePart.InlineData.Data,
+			DisplayName: responsePart.InlineData.DisplayName,
+		}
+		blob, err := normalizeImage(inline, limits)
+		if err != nil {
+			return nil, err
+		}
+		if blob == inline {
+			responseParts = append(responseParts, responsePart)
+			continue
+		}
+		changed = true
+		responseParts = append(responseParts, &genai.FunctionResponsePart{
+			InlineData: &genai.FunctionResponseBlob{MIMEType: blob.MIMEType, Data: blob.Data, DisplayName: blob.DisplayName},
+		})
+	}
+	if !changed {
+		return part, nil
+	}
+	response := *part.FunctionResponse
+	response.Parts = responseParts
+	normalized := *part
+	normalized.FunctionResponse = &response
+	return &normalized, nil
+}
+
+func isInlineImage(part *genai.Part) bool {
+	return part != nil && part.InlineData != nil && strings.HasPrefix(strings.ToLower(part.InlineData.MIMEType), "image/")
+}
+
+func isFunctionResponseImage(part *genai.FunctionResponsePart) bool {
+	return part != nil && part.InlineData != nil && strings.HasPrefix(strings.ToLower(part.InlineData.MIMEType), "image/")
+}
+
+// normalizeImage returns blob if it fits the limits, or otherwise a
+// converted and downsized copy.
+func normalizeImage(blob *genai.Blob, limits bedrockclient.ImageLimits) (*genai.Blob, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(blob.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	_, err = mapImageMediaType(strings.ToLower(blob.MIMEType))
+	if err == nil && fitsImageLimits(config.Width, config.Height, len(blob.Data), limits) {
+		return blob, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(blob.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	width, height := fitImage(img.Bounds().Dx(), img.Bounds().Dy(), limits)
+	for {
+		data, mimeType, err := encodeImage(resizeImage(img, width, height))
+		if err != nil {
+			return nil, err
+		}
+		if limits.MaxBytes <= 0 || len(data) <= limits.MaxBytes {
+			return &genai.Blob{MIMEType: mimeType, Data: data, DisplayName: blob.DisplayName}, nil
+		}
+		if width <= 1 && height <= 1 {
+			return nil, fmt.Errorf("image cannot be reduced to %d bytes", limits.MaxBytes)
+		}
+		// Each step roughly halves the number of pixels.
+		width, height = max(width*7/10, 1), max(height*7/10, 1)
+	}
+}
+
+func fitsImageLimits(width, height, size int, limits bedrockclient.ImageLimits) bool {
+	return (limits.MaxWidth <= 0 || width <= limits.MaxWidth) &&
+		(limits.MaxHeight <= 0 || height <= limits.MaxHeight) &&
+		(limits.MaxBytes <= 0 || size <= limits.MaxBytes)
+}
+
+// fitImage scales width and height down to the pixel limits, keeping the
+// aspect ratio.
+func fitImage(width, height int, limits bedrockclient.ImageLimits) (int, int) {
+	scale := 1.0
+	if limits.MaxWidth > 0 && width > limits.MaxWidth {
+		scale = float64(limits.MaxWidth) / float64(width)
+	}
+	if limits.MaxHeight > 0 && height > limits.MaxHeight {
+		scale = min(scale, float64(limits.MaxHeight)/float64(height))
+	}
+	if scale == 1 {
+		return width, height
+	}
+	return max(int(float64(width)*scale), 1), max(int(float64(height)*scale), 1)
+}
+
+func resizeImage(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() == width && bounds.Dy() == height {
+		return img
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// encodeImage encodes images with transparency as PNG and others as JPEG,
+// which is far smaller for photos and screenshots alike.
+func encodeImage(img image.Image) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if !isOpaque(img) {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", fmt.Errorf("failed to encode image: %w", err)
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package converters
 import (
 	"encoding/json"
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/dingdinglz/adk-go-bedrock/internal/bedrockclient"
@@ -95,6 +96,11 @@ func PartToContentBlock(part *genai.Part, role bedrockclient.ChatMessageType) (b
 		return bedrockclient.Message{}, nil
 	}
 
+	// Reasoning from a previous model turn, replayed with its signature
+	if part.Thought {
+		return thoughtToBlock(part, role), nil
+	}
+
 	// Text content
 	if part.Text != "" {
 		return bedrockclient.Message{
@@ -134,6 +140,100 @@ func PartToContentBlock(part *genai.Part, role bedrockclient.ChatMessageType) (b
 	return bedrockclient.Message{}, nil
 }
 
+// thoughtToBlock converts a thought part back to the reasoning block it was
+// created from. Thoughts without text carry redacted reasoning in their
+// signature.
+func thoughtToBlock(part *genai.Part, role bedrockclient.ChatMessageType) bedrockclient.Message {
+	if part.Text == "" {
+		return bedrockclient.Message{
+			Role:    role,
+			Type:    bedrockclient.MessageTypeRedactedThinking,
+			Content: string(part.ThoughtSignature),
+		}
+	}
+	return bedrockclient.Message{
+		Role:      role,
+		Type:      bedrockclient.MessageTypeThinking,
+		Content:   part.Text,
+		Signature: string(part.ThoughtSignature),
+	}
+}
+
+// ThinkingConfigToBudget maps a genai thinking configuration to an extended
+// thinking budget in tokens. Zero means thinking is disabled.
+func ThinkingConfigToBudget(config *genai.ThinkingConfig) int {
+	const defaultBudget = 4096
+	if config == nil {
+		return 0
+	}
+
+	if config.ThinkingBudget != nil {
+		switch budget := int(*config.ThinkingBudget); {
+		case budget == 0:
+			return 0
+		case budget < 0: // dynamic thinking
+			return defaultBudget
+		default:
+			return max(budget, bedrockclient.MinThinkingBudget)
+		}
+	}
+
+	switch config.ThinkingLevel {
+	case genai.ThinkingLevelMinimal, genai.ThinkingLevelLow:
+		return bedrockclient.MinThinkingBudget
+	case genai.ThinkingLevelMedium:
+		return defaultBudget
+	case genai.ThinkingLevelHigh:
+		return 16384
+	}
+
+	if config.IncludeThoughts {
+		return defaultBudget
+	}
+	return 0
+}
+
+// ThinkingConfigToReasoningEffort maps the thinking level of a genai
+// thinking configuration to a reasoning effort. Models that take an effort
+// fall back to the thinking budget without a level.
+func ThinkingConfigToReasoningEffort(config *genai.ThinkingConfig) string {
+	if config == nil {
+		return ""
+	}
+	switch config.ThinkingLevel {
+	case genai.ThinkingLevelMinimal, genai.ThinkingLevelLow:
+		return bedrockclient.ReasoningEffortLow
+	case genai.ThinkingLevelMedium:
+		return bedrockclient.ReasoningEffortMedium
+	case genai.ThinkingLevelHigh:
+		return bedrockclient.ReasoningEffortHigh
+	}
+	return ""
+}
+
+// ResponseSchemaToMap returns the JSON schema the response must match, or
+// nil if the response is free-form. ResponseJsonSchema takes precedence over
+// ResponseSchema, and JSON output without a schema accepts any JSON value.
+func ResponseSchemaToMap(config *genai.GenerateContentConfig) (map[string]any, error) {
+	switch {
+	case config.ResponseJsonSchema != nil:
+		raw, err := json.Marshal(config.ResponseJsonSchema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode response JSON schema: %w", err)
+		}
+		var schema map[string]any
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			return nil, fmt.Errorf("failed to decode response JSON schema: %w", err)
+		}
+		return schema, nil
+	case config.ResponseSchema != nil:
+		return SchemaToMap(config.ResponseSchema), nil
+	case config.ResponseMIMEType == "application/json":
+		return map[string]any{}, nil
+	}
+	return nil, nil
+}
+
 func inlineDataToBlock(blob *genai.Blob, role bedrockclient.ChatMessageType) (bedrockclient.Message, error) {
 	if blob == nil {
 		return bedrockclient.Message{}, nil
@@ -141,6 +241,31 @@ func inlineDataToBlock(blob *genai.Blob, role bedrockclient.ChatMessageType) (be
 
 	mimeType := strings.ToLower(blob.MIMEType)
 
+	// Handle documents. Parameters such as the charset are dropped.
+	if mediaType, _, _ := strings.Cut(mimeType, ";"); bedrockclient.DocumentFormat(strings.TrimSpace(mediaType)) != "" {
+		return bedrockclient.Message{
+			Role:     role,
+			Type:     bedrockclient.MessageTypeDocument,
+			MimeType: strings.TrimSpace(mediaType),
+			Name:     blob.DisplayName,
+			Content:  string(blob.Data),
+		}, nil
+	}
+
+	// Handle videos
+	if strings.HasPrefix(mimeType, "video/") {
+		mediaType, err := mapVideoMediaType(mimeType)
+		if err != nil {
+			return bedrockclient.Message{}, err
+		}
+		return bedrockclient.Message{
+			Role:     role,
+			Type:     bedrockclient.MessageTypeVideo,
+			MimeType: mediaType,
+			Content:  string(blob.Data),
+		}, nil
+	}
+
 	// Handle images
 	if strings.HasPrefix(mimeType, "image/") {
 		mediaType, err := mapImageMediaType(mimeType)
@@ -173,6 +298,15 @@ func mapImageMediaType(mimeType string) (string, error) {
 	}
 }
 
+func mapVideoMediaType(mimeType string) (string, error) {
+	mediaType, _, _ := strings.Cut(mimeType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	if bedrockclient.VideoFormat(mediaType) == "" {
+		return "", fmt.Errorf("unsupported video media type: %s", mimeType)
+	}
+	return mediaType, nil
+}
+
 func fileDataToBlock(fileData *genai.FileData, role bedrockclient.ChatMessageType) (bedrockclient.Message, error) {
 	if fileData == nil {
 		return bedrockclient.Message{}, nil
@@ -180,6 +314,47 @@ func fileDataToBlock(fileData *genai.FileData, role bedrockclient.ChatMessageTyp
 
 	mimeType := strings.ToLower(fileData.MIMEType)
 
+	// Objects in S3 are referenced, the client inlines them for models
+	// that cannot read S3.
+	if _, key, ok := bedrockclient.ParseS3URI(fileData.FileURI); ok {
+		location := &bedrockclient.S3Location{URI: fileData.FileURI}
+		if mediaType, _, _ := strings.Cut(mimeType, ";"); bedrockclient.DocumentFormat(strings.TrimSpace(mediaType)) != "" {
+			name := fileData.DisplayName
+			if name == "" {
+				name = path.Base(key)
+			}
+			return bedrockclient.Message{
+				Role:       role,
+				Type:       bedrockclient.MessageTypeDocument,
+				MimeType:   strings.TrimSpace(mediaType),
+				Name:       name,
+				S3Location: location,
+			}, nil
+		}
+		if strings.HasPrefix(mimeType, "video/") {
+			mediaType, err := mapVideoMediaType(mimeType)
+			if err != nil {
+				return bedrockclient.Message{}, err
+			}
+			return bedrockclient.Message{
+				Role:       role,
+				Type:       bedrockclient.MessageTypeVideo,
+				MimeType:   mediaType,
+				S3Location: location,
+			}, nil
+		}
+		mediaType, err := mapImageMediaType(mimeType)
+		if err != nil {
+			return bedrockclient.Message{}, fmt.Errorf("unsupported MIME type for S3 object: %s", mimeType)
+		}
+		return bedrockclient.Message{
+			Role:       role,
+			Type:       "image",
+			MimeType:   mediaType,
+			S3Location: location,
+		}, nil
+	}
+
 	// Handle images via URL
 	if strings.HasPrefix(mimeType, "image/") {
 		return bedrockclient.Message{
@@ -203,22 +378,97 @@ func functionResponseToBlock(resp *genai.FunctionResponse, role bedrockclient.Ch
 		return bedrockclient.Message{}, fmt.Errorf("FunctionResponse.ID is required for tool call correlation (function: %s)", resp.Name)
 	}
 
-	// Convert the response to JSON string
-	var content string
-	if resp.Response != nil {
-		jsonBytes, err := json.Marshal(resp.Response)
-		if err != nil {
-			return bedrockclient.Message{}, fmt.Errorf("failed to marshal function response: %w", err)
-		}
-		content = string(jsonBytes)
+	content, err := toolResultText(resp.Response)
+	if err != nil {
+		return bedrockclient.Message{}, err
 	}
-
-	return bedrockclient.Message{
+	message := bedrockclient.Message{
 		Type:      "tool_result",
 		ToolUseID: resp.ID,
 		Content:   content,
 		Role:      role,
-	}, nil
+		ToolError: isToolError(resp.Response),
+	}
+
+	// Media returned by the tool are sent as blocks of the result, next to
+	// its text.
+	for _, part := range resp.Parts {
+		if part == nil {
+			continue
+		}
+		var block bedrockclient.Message
+		switch {
+		case part.InlineData != nil:
+			block, err = inlineDataToBlock(&genai.Blob{
+				Data:        part.InlineData.Data,
+				DisplayName: part.InlineData.DisplayName,
+				MIMEType:    part.InlineData.MIMEType,
+			}, role)
+		case part.FileData != nil:
+			block, err = fileDataToBlock(&genai.FileData{
+				DisplayName: part.FileData.DisplayName,
+				FileURI:     part.FileData.FileURI,
+				MIMEType:    part.FileData.MIMEType,
+			}, role)
+		default:
+			continue
+		}
+		if err != nil {
+			return bedrockclient.Message{}, fmt.Errorf("failed to convert function response part: %w", err)
+		}
+		if len(message.Parts) == 0 && content != "" {
+			message.Parts = append(message.Parts, bedrockclient.Message{Role: role, Type: "text", Content: content})
+		}
+		message.Parts = append(message.Parts, block)
+	}
+	return message, nil
+}
+
+// toolResultKeys are the keys tools commonly return their whole result
+// under, e.g. the function tools of ADK for results that are not maps, or
+// ADK for the error of a failed tool.
+var toolResultKeys = []string{"output", "result", "error"}
+
+// isToolError reports whether a function response is the error of a failed
+// tool, which ADK reports under the "error" key. Only a message, true or
+// an error object count; tools may return other values, such as an error
+// code of 0, on success.
+func isToolError(response map[string]any) bool {
+	switch err := response["error"].(type) {
+	case string:
+		return err != ""
+	case bool:
+		return err
+	case map[string]any:
+		return len(err) > 0
+	default:
+		return false
+	}
+}
+
+// toolResultText returns the text of a function response. A response that
+// only holds a string under a well-known key is sent as that string,
+// others as JSON.
+func toolResultText(response map[string]any) (string, error) {
+	if len(response) == 0 {
+		return "", nil
+	}
+	var value any = response
+	if len(response) == 1 {
+		for _, key := range toolResultKeys {
+			if v, ok := response[key]; ok {
+				value = v
+			}
+		}
+	}
+	if text, ok := value.(string); ok {
+		return text, nil
+	}
+	jsonBytes, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal function response: %w", err)
+	}
+	return string(jsonBytes), nil
 }
 
 func functionCallToBlock(call *genai.FunctionCall, role bedrockclient.ChatMessageType) (bedrockclient.Message, error) {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/dingdinglz/adk-go-bedrock/internal/bedrockclient"
 	"github.com/tmc/langchaingo/llms"
 	"google.golang.org/adk/model"
 	"google.golang.org/genai"
@@ -22,7 +23,10 @@ func MessageToLLMResponse(msg *llms.ContentResponse) (*model.LLMResponse, error)
 		Parts: make([]*genai.Part, 0),
 	}
 
-	// TODO: Collect citations from text blocks
+	// Reasoning comes first, as the model produced it before the answer.
+	// The parts are kept even when thoughts were not requested because
+	// Bedrock needs them replayed in later tool-use turns.
+	content.Parts = append(content.Parts, ThinkingBlocksToParts(avaibleChoice.GenerationInfo)...)
 
 	if avaibleChoice.Content != "" {
 		content.Parts = append(content.Parts, &genai.Part{
@@ -51,27 +55,92 @@ func MessageToLLMResponse(msg *llms.ContentResponse) (*model.LLMResponse, error)
 	}
 
 	resp := &model.LLMResponse{
-		Content:       content,
-		UsageMetadata: usage,
-		FinishReason:  StopReasonToFinishReason(avaibleChoice.StopReason),
+		Content:          content,
+		UsageMetadata:    usage,
+		CustomMetadata:   CacheUsageToCustomMetadata(avaibleChoice.GenerationInfo),
+		CitationMetadata: CitationsToMetadata(avaibleChoice.GenerationInfo),
+		FinishReason:     StopReasonToFinishReason(avaibleChoice.StopReason),
 	}
 
 	return resp, nil
 }
 
+// ThinkingBlocksToParts converts the reasoning blocks of a response to
+// thought parts. Redacted blocks become thoughts without text whose
+// signature holds the encrypted data.
+func ThinkingBlocksToParts(info map[string]any) []*genai.Part {
+	blocks, _ := info[bedrockclient.GenerationInfoThinkingBlocks].([]bedrockclient.ThinkingBlock)
+	parts := make([]*genai.Part, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Redacted() {
+			parts = append(parts, &genai.Part{
+				Thought:          true,
+				ThoughtSignature: []byte(block.RedactedData),
+			})
+			continue
+		}
+		parts = append(parts, &genai.Part{
+			Text:             block.Text,
+			Thought:          true,
+			ThoughtSignature: []byte(block.Signature),
+		})
+	}
+	return parts
+}
+
 func UsageToMetadata(usage map[string]any) (*genai.GenerateContentResponseUsageMetadata, error) {
 	inputTokens, ok := usage["input_tokens"].(int)
 	outputTokens, ok2 := usage["output_tokens"].(int)
 	if !(ok && ok2) {
 		return nil, errors.New("failed to parse usage")
 	}
+	// Bedrock reports cached prompt tokens separately from input tokens,
+	// while genai counts them as part of the prompt.
+	cacheRead, _ := usage[bedrockclient.GenerationInfoCacheReadTokens].(int)
+	cacheCreation, _ := usage[bedrockclient.GenerationInfoCacheCreationTokens].(int)
+	promptTokens := inputTokens + cacheRead + cacheCreation
 	return &genai.GenerateContentResponseUsageMetadata{
-		PromptTokenCount:     int32(inputTokens),
-		CandidatesTokenCount: int32(outputTokens),
-		TotalTokenCount:      int32(inputTokens + outputTokens),
+		PromptTokenCount:        int32(promptTokens),
+		CachedContentTokenCount: int32(cacheRead),
+		CandidatesTokenCount:    int32(outputTokens),
+		TotalTokenCount:         int32(promptTokens + outputTokens),
 	}, nil
 }
 
+// CacheUsageToCustomMetadata exposes the prompt cache token counts of a
+// response as custom metadata. It returns nil when the cache was not used.
+func CacheUsageToCustomMetadata(usage map[string]any) map[string]any {
+	cacheRead, _ := usage[bedrockclient.GenerationInfoCacheReadTokens].(int)
+	cacheCreation, _ := usage[bedrockclient.GenerationInfoCacheCreationTokens].(int)
+	if cacheRead == 0 && cacheCreation == 0 {
+		return nil
+	}
+	return map[string]any{
+		bedrockclient.GenerationInfoCacheReadTokens:     cacheRead,
+		bedrockclient.GenerationInfoCacheCreationTokens: cacheCreation,
+	}
+}
+
+// CitationsToMetadata converts the citations of a grounded response. A
+// span supported by several documents yields one citation per document.
+func CitationsToMetadata(info map[string]any) *genai.CitationMetadata {
+	citations, _ := info[bedrockclient.GenerationInfoCitations].([]bedrockclient.Citation)
+	if len(citations) == 0 {
+		return nil
+	}
+	metadata := &genai.CitationMetadata{}
+	for _, c := range citations {
+		for _, id := range c.DocumentIDs {
+			metadata.Citations = append(metadata.Citations, &genai.Citation{
+				StartIndex: int32(c.Start),
+				EndIndex:   int32(c.End),
+				Title:      id,
+			})
+		}
+	}
+	return metadata
+}
+
 func StopReasonToFinishReason(sr string) genai.FinishReason {
 	switch sr {
 	case "end_turn":
@@ -82,6 +151,13 @@ func StopReasonToFinishReason(sr string) genai.FinishReason {
 		return genai.FinishReasonStop
 	case "tool_use":
 		return genai.FinishReasonStop
+	// Chat completions finish reasons
+	case "stop", "tool_calls":
+		return genai.FinishReasonStop
+	case "length":
+		return genai.FinishReasonMaxTokens
+	case "content_filter":
+		return genai.FinishReasonSafety
 	default:
 		return genai.FinishReasonUnspecified
 	}
@@ -0,0 +1,166 @@
+package converters
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/dingdinglz/adk-go-bedrock/internal/bedrockclient"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// Keys of model.LLMResponse.CustomMetadata set on streaming events.
+const (
+	// CustomMetadataStreamEvent holds the bedrockclient.StreamEventType
+	// of the event as a string.
+	CustomMetadataStreamEvent = "bedrock_stream_event"
+	// CustomMetadataFunctionCall holds the *genai.FunctionCall a tool
+	// call event belongs to.
+	CustomMetadataFunctionCall = "bedrock_function_call"
+)
+
+// StreamEventToLLMResponse converts a structured streaming event to a
+// partial response.
+//
+// Tool calls are reported through CustomMetadata instead of function call
+// parts, because ADK executes every function call part it sees and the
+// complete call is part of the final response anyway. seen tracks the
+// arguments already reported per call ID.
+func StreamEventToLLMResponse(event bedrockclient.StreamEvent, seen map[string]map[string]bool) (*model.LLMResponse, error) {
+	resp := &model.LLMResponse{
+		Partial: true,
+		// The content is empty but not nil, as ADK drops responses
+		// without content.
+		Content: &genai.Content{Role: "model"},
+		CustomMetadata: map[string]any{
+			CustomMetadataStreamEvent: string(event.Type),
+		},
+	}
+
+	if event.Type == bedrockclient.StreamEventUsage {
+		usage, err := UsageToMetadata(event.Usage)
+		if err != nil {
+			return nil, err
+		}
+		resp.UsageMetadata = usage
+		return resp, nil
+	}
+
+	if event.ToolCall.FunctionCall == nil {
+		return resp, nil
+	}
+	fc := &genai.FunctionCall{
+		ID:           event.ToolCall.ID,
+		Name:         event.ToolCall.FunctionCall.Name,
+		WillContinue: genai.Ptr(true),
+	}
+	switch event.Type {
+	case bedrockclient.StreamEventToolCallDelta:
+		if seen[fc.ID] == nil {
+			seen[fc.ID] = make(map[string]bool)
+		}
+		fc.PartialArgs = PartialArgs(event.ToolCall.FunctionCall.Arguments, seen[fc.ID])
+	case bedrockclient.StreamEventToolCallDone:
+		args := make(map[string]any)
+		if err := json.Unmarshal([]byte(event.ToolCall.FunctionCall.Arguments), &args); err != nil {
+			return nil, err
+		}
+		fc.Args = args
+		fc.WillContinue = genai.Ptr(false)
+		delete(seen, fc.ID)
+	}
+	resp.CustomMetadata[CustomMetadataFunctionCall] = fc
+	return resp, nil
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// PartialArgs returns the top-level scalar arguments that are complete in
+// the truncated JSON object args and not yet in seen, adding them to seen.
+// Nested objects and arrays are only reported with the completed call.
+func PartialArgs(args string, seen map[string]bool) []*genai.PartialArg {
+	dec := json.NewDecoder(strings.NewReader(args))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+
+	var partials []*genai.PartialArg
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		key, ok := tok.(string)
+		if !ok {
+			break
+		}
+		value, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if delim, ok := value.(json.Delim); ok {
+			if !skipValue(dec, delim) {
+				break
+			}
+			continue
+		}
+		// A number at the very end of the input may still grow.
+		if _, ok := value.(json.Number); ok && dec.InputOffset() == int64(len(args)) {
+			break
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		partials = append(partials, scalarToPartialArg(jsonPath(key), value))
+	}
+	return partials
+}
+
+// skipValue consumes the rest of the object or array opened by delim and
+// reports whether it was complete.
+func skipValue(dec *json.Decoder, delim json.Delim) bool {
+	if delim != '{' && delim != '[' {
+		return false
+	}
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return true
+}
+
+func scalarToPartialArg(path string, value any) *genai.PartialArg {
+	arg := &genai.PartialArg{JsonPath: path}
+	switch v := value.(type) {
+	case nil:
+		arg.NULLValue = "NULL_VALUE"
+	case bool:
+		arg.BoolValue = genai.Ptr(v)
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			arg.NumberValue = genai.Ptr(f)
+		}
+	case string:
+		arg.StringValue = v
+	}
+	return arg
+}
+
+// jsonPath returns the RFC 9535 path of a top-level member.
+func jsonPath(key string) string {
+	if identifierPattern.MatchString(key) {
+		return "$." + key
+	}
+	return "$['" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(key) + "']"
+}
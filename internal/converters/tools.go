@@ -1,7 +1,6 @@
 package converters
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -326,13 +325,10 @@ func ToolConfigToToolChoice(config *genai.ToolConfig) (interface{}, error) {
 	case toolChoiceAny:
 		return "required", nil
 	case toolChoiceTool:
-		resp, _ := json.Marshal(map[string]any{
-			"type": "tool",
-			"function": map[string]any{
-				"name": resolved.toolName,
-			},
-		})
-		return resp, nil
+		return llms.ToolChoice{
+			Type:     "function",
+			Function: &llms.FunctionReference{Name: resolved.toolName},
+		}, nil
 	default:
 		return nil, fmt.Errorf("unexpected tool choice kind: %d", resolved.kind)
 	}
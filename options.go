@@ -0,0 +1,170 @@
+package adkgobedrock
+
+import (
+	"context"
+
+	"github.com/dingdinglz/adk-go-bedrock/internal/bedrockclient"
+	"github.com/tmc/langchaingo/llms"
+	"google.golang.org/genai"
+)
+
+// Option configures a Bedrock model created by NewModel.
+type Option func(*bedrockModel)
+
+// GuardrailConfig selects the Bedrock guardrail applied to every call.
+type GuardrailConfig = bedrockclient.GuardrailConfig
+
+// Hooks observe the Bedrock calls made by a model. Every hook is optional.
+type Hooks struct {
+	// BeforeCall runs before each call attempt with the converted request.
+	// It may adjust the options; returning an error aborts the call.
+	BeforeCall func(ctx context.Context, modelID string, messages []Message, options *llms.CallOptions) error
+	// AfterCall runs after each call attempt with its result. A stream
+	// the consumer stopped early ends with an error wrapping ErrStreamStopped.
+	AfterCall func(ctx context.Context, modelID string, resp *llms.ContentResponse, err error)
+	// OnRetry runs before a failed attempt is retried. attempt is the
+	// number of the attempt that is about to start, beginning at 2.
+	OnRetry func(ctx context.Context, attempt int, err error)
+}
+
+// MetadataCohereDocuments is the llms.CallOptions.Metadata key of the
+// []map[string]string documents a Cohere Command R response is grounded
+// on. Set it from Hooks.BeforeCall; citations are returned in
+// model.LLMResponse.CitationMetadata. Text documents of the request are
+// added after them.
+const MetadataCohereDocuments = bedrockclient.MetadataCohereDocuments
+
+// WithConverse routes the model through the Bedrock Converse / ConverseStream
+// API instead of the provider-specific InvokeModel bodies. Converse gives
+// every model family that supports it (Nova, Llama, Mistral, Cohere, ...)
+// tool use and streaming through one code path. TopK is only sent to the
+// Anthropic, Nova and Cohere models, Converse has no field for it.
+func WithConverse() Option {
+	return func(m *bedrockModel) {
+		m.useConverse = true
+	}
+}
+
+// WithGenerateContentConfig sets default generation settings. Fields set on
+// the GenerateContentConfig of an ADK request take precedence.
+func WithGenerateContentConfig(config *genai.GenerateContentConfig) Option {
+	return func(m *bedrockModel) {
+		m.defaultConfig = config
+	}
+}
+
+// WithProvider pins the model to the provider registered under name,
+// bypassing model ID inference for this model only.
+func WithProvider(name string) Option {
+	return func(m *bedrockModel) {
+		m.providerName = name
+	}
+}
+
+// WithRetryPolicy retries failed Bedrock calls according to policy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(m *bedrockModel) {
+		m.retryPolicy = &policy
+	}
+}
+
+// WithHooks installs hooks around every Bedrock call.
+func WithHooks(hooks Hooks) Option {
+	return func(m *bedrockModel) {
+		m.hooks = hooks
+	}
+}
+
+// WithGuardrail applies a Bedrock guardrail to every call.
+func WithGuardrail(config GuardrailConfig) Option {
+	return func(m *bedrockModel) {
+		m.guardrail = &config
+	}
+}
+
+// WithAnthropicBeta enables Anthropic beta features, e.g.
+// "token-efficient-tools-2025-02-19", on Claude models.
+func WithAnthropicBeta(flags ...string) Option {
+	return func(m *bedrockModel) {
+		m.anthropicBeta = append(m.anthropicBeta, flags...)
+	}
+}
+
+// CachePolicy decides where prompt cache breakpoints are placed.
+type CachePolicy = bedrockclient.CachePolicy
+
+// DefaultCachePolicy caches the system prompt, the tools and the
+// conversation up to the latest message.
+var DefaultCachePolicy = bedrockclient.DefaultCachePolicy
+
+// WithPromptCaching enables prompt caching on models that support it
+// (Claude, and Nova through Converse). Without a policy, DefaultCachePolicy
+// is used. Cached token counts are reported in
+// UsageMetadata.CachedContentTokenCount and in the custom metadata of
+// each response.
+func WithPromptCaching(policy ...CachePolicy) Option {
+	return func(m *bedrockModel) {
+		p := DefaultCachePolicy
+		if len(policy) > 0 {
+			p = policy[0]
+		}
+		m.cachePolicy = &p
+	}
+}
+
+// WithEmptyContentsPlaceholder sets the user prompt that is sent when an
+// ADK request has no contents, since Bedrock requires at least one message.
+func WithEmptyContentsPlaceholder(text string) Option {
+	return func(m *bedrockModel) {
+		m.emptyContentsPlaceholder = text
+	}
+}
+
+// ObjectGetter reads objects from S3. Models other than Nova cannot read
+// s3:// FileData parts themselves, so the adapter fetches and inlines
+// them with it.
+type ObjectGetter = bedrockclient.ObjectGetter
+
+// ObjectGetterFunc adapts a function to an ObjectGetter.
+type ObjectGetterFunc = bedrockclient.ObjectGetterFunc
+
+// WithObjectGetter sets the getter s3:// FileData parts are fetched with
+// for models that cannot read S3.
+func WithObjectGetter(getter ObjectGetter) Option {
+	return func(m *bedrockModel) {
+		m.objectGetter = getter
+	}
+}
+
+// WithS3BucketOwner sets the account ID that must own the buckets of the
+// s3:// FileData parts Nova reads.
+func WithS3BucketOwner(accountID string) Option {
+	return func(m *bedrockModel) {
+		m.s3BucketOwner = accountID
+	}
+}
+
+// WithImageNormalization converts inline images the model does not accept,
+// such as BMP or TIFF, to PNG or JPEG and downsizes images above its pixel
+// and byte limits, including the images of function responses. The oldest
+// images beyond the number a model accepts per request are replaced with a
+// placeholder text, or dropped from function responses. Images fetched by
+// a URLFetcher are not normalized.
+func WithImageNormalization() Option {
+	return func(m *bedrockModel) {
+		m.normalizeImages = true
+	}
+}
+
+// URLFetcher downloads image URLs for inlining, with a size cap, an
+// allow-list of hosts and an in-memory cache. It only connects to public
+// addresses unless AllowPrivateNetworks is set.
+type URLFetcher = bedrockclient.URLFetcher
+
+// WithURLFetcher inlines http(s) FileData images fetched with fetcher.
+// Bedrock models do not read image URLs themselves.
+func WithURLFetcher(fetcher *URLFetcher) Option {
+	return func(m *bedrockModel) {
+		m.urlFetcher = fetcher
+	}
+}
@@ -0,0 +1,99 @@
+package adkgobedrock
+
+import (
+	"github.com/dingdinglz/adk-go-bedrock/internal/bedrockclient"
+	"github.com/dingdinglz/adk-go-bedrock/internal/converters"
+)
+
+// Provider adapts one Bedrock model family to the InvokeModel API: it builds
+// the request body, parses the response body and parses streamed chunks.
+// Implement it and call RegisterProvider to add a family without forking
+// the adapter.
+type Provider = bedrockclient.Provider
+
+// StreamParser decodes the chunks of a streamed InvokeModel response.
+type StreamParser = bedrockclient.StreamParser
+
+// Message is a single converted content part handed to a Provider.
+type Message = bedrockclient.Message
+
+// ChatMessageType is the role of a Message.
+type ChatMessageType = bedrockclient.ChatMessageType
+
+// Roles of a Message.
+const (
+	ChatMessageTypeAI       = bedrockclient.ChatMessageTypeAI
+	ChatMessageTypeHuman    = bedrockclient.ChatMessageTypeHuman
+	ChatMessageTypeSystem   = bedrockclient.ChatMessageTypeSystem
+	ChatMessageTypeGeneric  = bedrockclient.ChatMessageTypeGeneric
+	ChatMessageTypeFunction = bedrockclient.ChatMessageTypeFunction
+	ChatMessageTypeTool     = bedrockclient.ChatMessageTypeTool
+)
+
+// ErrUnsupportedProvider is returned when no provider serves a model ID.
+var ErrUnsupportedProvider = bedrockclient.ErrUnsupportedProvider
+
+// ErrStreamStopped ends a streamed call whose consumer stopped iterating.
+var ErrStreamStopped = bedrockclient.ErrStreamStopped
+
+// ErrStructuredOutput is returned when a response does not match the
+// ResponseSchema of the request.
+var ErrStructuredOutput = bedrockclient.ErrStructuredOutput
+
+// UnsupportedInputError is returned when a request carries a kind of input
+// the model does not accept, such as a video for a model other than Nova
+// Lite, Pro or Premier.
+type UnsupportedInputError = bedrockclient.UnsupportedInputError
+
+// StreamError is an error event received in a response stream, such as an
+// overloaded_error chunk or a ModelStreamErrorException.
+type StreamError = bedrockclient.StreamError
+
+// RegisterProvider adds a provider to the global registry. A provider with
+// the same name is replaced, so built-in families can be overridden too.
+func RegisterProvider(p Provider) {
+	bedrockclient.DefaultRegistry.Register(p)
+}
+
+// OpenAICompatibleConfig describes a model family that takes the OpenAI
+// style chat completions body, such as Qwen3, Kimi or MiniMax.
+type OpenAICompatibleConfig = bedrockclient.OpenAICompatibleConfig
+
+// ThinkingStyle selects how an OpenAI compatible model is told to think.
+type ThinkingStyle = bedrockclient.ThinkingStyle
+
+// Thinking styles of OpenAI compatible models.
+const (
+	ThinkingStyleNone            = bedrockclient.ThinkingStyleNone
+	ThinkingStyleReasoningEffort = bedrockclient.ThinkingStyleReasoningEffort
+	ThinkingStyleEnableThinking  = bedrockclient.ThinkingStyleEnableThinking
+	ThinkingStyleThinkingType    = bedrockclient.ThinkingStyleThinkingType
+)
+
+// NewOpenAICompatibleProvider returns a provider for a model family that
+// takes the chat completions body. Register it with RegisterProvider and
+// pin model IDs or ARNs outside its ModelPrefixes to its name.
+func NewOpenAICompatibleProvider(config OpenAICompatibleConfig) Provider {
+	return bedrockclient.NewOpenAICompatibleProvider(config)
+}
+
+// PinModel binds a model ID or ARN to the provider registered under
+// providerName. Use it for identifiers the family cannot be inferred from,
+// such as application inference profile, provisioned throughput and
+// imported model ARNs. Legacy models take the "ai21-jurassic",
+// "mistral-instruct" and "cohere-command" providers.
+func PinModel(modelID, providerName string) {
+	bedrockclient.DefaultRegistry.Pin(modelID, providerName)
+}
+
+// Keys of model.LLMResponse.CustomMetadata on the partial responses that
+// GenerateContent streams for tool calls and usage updates.
+const (
+	// CustomMetadataStreamEvent holds the event type: "tool_call_start",
+	// "tool_call_delta", "tool_call_done" or "usage".
+	CustomMetadataStreamEvent = converters.CustomMetadataStreamEvent
+	// CustomMetadataFunctionCall holds the *genai.FunctionCall of a tool
+	// call event. Deltas carry the newly completed top-level arguments in
+	// PartialArgs, and the done event carries the complete Args.
+	CustomMetadataFunctionCall = converters.CustomMetadataFunctionCall
+)
@@ -7,21 +7,47 @@ import (
 	"github.com/dingdinglz/adk-go-bedrock/internal/converters"
 	"github.com/tmc/langchaingo/llms"
 	"google.golang.org/adk/model"
+	"google.golang.org/genai"
 )
 
 func (m *bedrockModel) convertRequest(req *model.LLMRequest) ([]bedrockclient.Message, llms.CallOptions, error) {
-	messages, err := converters.ContentsToMessages(req.Contents)
+	contents := req.Contents
+	if m.normalizeImages {
+		var err error
+		if contents, err = converters.NormalizeImages(contents, m.client.ImageLimits(m.modelName)); err != nil {
+			return []bedrockclient.Message{}, llms.CallOptions{}, fmt.Errorf("failed to normalize images: %w", err)
+		}
+	}
+	messages, err := converters.ContentsToMessages(contents)
 	if err != nil {
 		return []bedrockclient.Message{}, llms.CallOptions{}, fmt.Errorf("failed to convert contents: %w", err)
 	}
+	if m.s3BucketOwner != "" {
+		for i := range messages {
+			if location := messages[i].S3Location; location != nil {
+				location.BucketOwner = m.s3BucketOwner
+			}
+		}
+	}
 
 	option := llms.CallOptions{}
 	option.MaxTokens = m.maxTokens
 
-	if req.Config != nil {
+	if m.guardrail != nil {
+		bedrockclient.SetMetadata(&option, bedrockclient.MetadataGuardrail, m.guardrail)
+	}
+	if len(m.anthropicBeta) > 0 {
+		bedrockclient.SetMetadata(&option, bedrockclient.MetadataAnthropicBeta, m.anthropicBeta)
+	}
+	if m.cachePolicy != nil {
+		bedrockclient.SetMetadata(&option, bedrockclient.MetadataCachePolicy, m.cachePolicy)
+	}
+
+	config := mergeGenerateContentConfig(m.defaultConfig, req.Config)
+	if config != nil {
 		// System instruction
-		if req.Config.SystemInstruction != nil {
-			systemPrompt := converters.SystemInstructionToSystem(req.Config.SystemInstruction)
+		if config.SystemInstruction != nil {
+			systemPrompt := converters.SystemInstructionToSystem(config.SystemInstruction)
 			messages = append([]bedrockclient.Message{
 				{
 					Role:    bedrockclient.ChatMessageTypeSystem,
@@ -32,30 +58,47 @@ func (m *bedrockModel) convertRequest(req *model.LLMRequest) ([]bedrockclient.Me
 		}
 
 		// Generation parameters
-		if req.Config.Temperature != nil {
-			option.Temperature = float64(*req.Config.Temperature)
+		if config.Temperature != nil {
+			option.Temperature = float64(*config.Temperature)
+		}
+		if config.TopP != nil {
+			option.TopP = float64(*config.TopP)
+		}
+		if config.TopK != nil {
+			option.TopK = int(*config.TopK)
+		}
+		if len(config.StopSequences) > 0 {
+			option.StopWords = config.StopSequences
 		}
-		if req.Config.TopP != nil {
-			option.TopP = float64(*req.Config.TopP)
+		if config.MaxOutputTokens > 0 {
+			option.MaxTokens = int(config.MaxOutputTokens)
 		}
-		if req.Config.TopK != nil {
-			option.TopK = int(*req.Config.TopK)
+
+		// Extended thinking
+		if config.ThinkingConfig != nil {
+			bedrockclient.SetMetadata(&option, bedrockclient.MetadataThinkingBudget, converters.ThinkingConfigToBudget(config.ThinkingConfig))
+		}
+		if effort := converters.ThinkingConfigToReasoningEffort(config.ThinkingConfig); effort != "" {
+			bedrockclient.SetMetadata(&option, bedrockclient.MetadataReasoningEffort, effort)
 		}
-		if len(req.Config.StopSequences) > 0 {
-			option.StopWords = req.Config.StopSequences
+
+		// Structured output
+		schema, err := converters.ResponseSchemaToMap(config)
+		if err != nil {
+			return []bedrockclient.Message{}, llms.CallOptions{}, err
 		}
-		if req.Config.MaxOutputTokens > 0 {
-			option.MaxTokens = int(req.Config.MaxOutputTokens)
+		if schema != nil {
+			bedrockclient.SetMetadata(&option, bedrockclient.MetadataResponseSchema, schema)
 		}
 
 		// Tools
-		if len(req.Config.Tools) > 0 {
-			option.Tools = converters.ToolsToBedrockTools(req.Config.Tools)
+		if len(config.Tools) > 0 {
+			option.Tools = converters.ToolsToBedrockTools(config.Tools)
 		}
 
 		// Tool choice from ToolConfig
-		if req.Config.ToolConfig != nil {
-			toolChoice, err := converters.ToolConfigToToolChoice(req.Config.ToolConfig)
+		if config.ToolConfig != nil {
+			toolChoice, err := converters.ToolConfigToToolChoice(config.ToolConfig)
 			if err != nil {
 				return []bedrockclient.Message{}, llms.CallOptions{}, err
 			}
@@ -64,3 +107,53 @@ func (m *bedrockModel) convertRequest(req *model.LLMRequest) ([]bedrockclient.Me
 	}
 	return messages, option, nil
 }
+
+// mergeGenerateContentConfig overlays the fields set on override onto base.
+// Either config may be nil.
+func mergeGenerateContentConfig(base, override *genai.GenerateContentConfig) *genai.GenerateContentConfig {
+	if base == nil {
+		return override
+	}
+	if override == nil {
+		return base
+	}
+
+	merged := *base
+	if override.SystemInstruction != nil {
+		merged.SystemInstruction = override.SystemInstruction
+	}
+	if override.Temperature != nil {
+		merged.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		merged.TopP = override.TopP
+	}
+	if override.TopK != nil {
+		merged.TopK = override.TopK
+	}
+	if len(override.StopSequences) > 0 {
+		merged.StopSequences = override.StopSequences
+	}
+	if override.MaxOutputTokens > 0 {
+		merged.MaxOutputTokens = override.MaxOutputTokens
+	}
+	if len(override.Tools) > 0 {
+		merged.Tools = override.Tools
+	}
+	if override.ToolConfig != nil {
+		merged.ToolConfig = override.ToolConfig
+	}
+	if override.ThinkingConfig != nil {
+		merged.ThinkingConfig = override.ThinkingConfig
+	}
+	// The two schema fields are alternatives, a request schema replaces
+	// either of the defaults.
+	if override.ResponseSchema != nil || override.ResponseJsonSchema != nil {
+		merged.ResponseSchema = override.ResponseSchema
+		merged.ResponseJsonSchema = override.ResponseJsonSchema
+	}
+	if override.ResponseMIMEType != "" {
+		merged.ResponseMIMEType = override.ResponseMIMEType
+	}
+	return &merged
+}
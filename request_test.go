@@ -0,0 +1,323 @@
+package adkgobedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/dingdinglz/adk-go-bedrock/internal/bedrockclient"
+	"golang.org/x/image/bmp"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestConvertRequestDefaults(t *testing.T) {
+	m := NewModel(nil, "us.anthropic.claude-sonnet-4-5-20250929-v1:0", 0,
+		WithGenerateContentConfig(&genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText("default system", genai.RoleUser),
+			Temperature:       genai.Ptr[float32](0.2),
+			MaxOutputTokens:   1024,
+		}),
+		WithGuardrail(GuardrailConfig{Identifier: "gr-1", Version: "1"}),
+		WithAnthropicBeta("token-efficient-tools-2025-02-19"),
+		WithEmptyContentsPlaceholder("go"),
+	).(*bedrockModel)
+
+	req := &model.LLMRequest{
+		Config: &genai.GenerateContentConfig{
+			MaxOutputTokens: 2048,
+		},
+	}
+	m.maybeAppendUserContent(req)
+	msgs, options, err := m.convertRequest(req)
+	if err != nil {
+		t.Fatalf("convertRequest() error = %v", err)
+	}
+
+	if len(msgs) != 2 || msgs[0].Role != bedrockclient.ChatMessageTypeSystem || msgs[0].Content != "default system\n" {
+		t.Errorf("system prompt not taken from defaults: %+v", msgs)
+	}
+	if msgs[1].Content != "go" {
+		t.Errorf("placeholder = %q, want %q", msgs[1].Content, "go")
+	}
+	if options.MaxTokens != 2048 {
+		t.Errorf("MaxTokens = %d, want request value 2048", options.MaxTokens)
+	}
+	if options.Temperature != float64(float32(0.2)) {
+		t.Errorf("Temperature = %v, want default 0.2", options.Temperature)
+	}
+	if g, _ := options.Metadata[bedrockclient.MetadataGuardrail].(*GuardrailConfig); g == nil || g.Identifier != "gr-1" {
+		t.Errorf("guardrail metadata = %v", options.Metadata[bedrockclient.MetadataGuardrail])
+	}
+	if beta, _ := options.Metadata[bedrockclient.MetadataAnthropicBeta].([]string); len(beta) != 1 {
+		t.Errorf("anthropic beta metadata = %v", options.Metadata[bedrockclient.MetadataAnthropicBeta])
+	}
+}
+
+func TestConvertRequestResponseSchema(t *testing.T) {
+	m := NewModel(nil, "us.amazon.nova-pro-v1:0", 0).(*bedrockModel)
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Weather in Paris?", genai.RoleUser)},
+		Config: &genai.GenerateContentConfig{
+			ResponseMIMEType: "application/json",
+			ResponseSchema: &genai.Schema{
+				Type:       genai.TypeObject,
+				Properties: map[string]*genai.Schema{"temp": {Type: genai.TypeNumber}},
+				Required:   []string{"temp"},
+			},
+		},
+	}
+	_, options, err := m.convertRequest(req)
+	if err != nil {
+		t.Fatalf("convertRequest() error = %v", err)
+	}
+	schema, _ := options.Metadata[bedrockclient.MetadataResponseSchema].(map[string]any)
+	if schema["type"] != "object" || schema["required"] == nil {
+		t.Errorf("response schema metadata = %v", options.Metadata[bedrockclient.MetadataResponseSchema])
+	}
+}
+
+type httpClientFunc func(*http.Request) (*http.Response, error)
+
+func (f httpClientFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestGenerateContentResponseSchemaWithDefaults(t *testing.T) {
+	var body map[string]any
+	client := bedrockruntime.New(bedrockruntime.Options{
+		Region: "us-east-1",
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		}),
+		HTTPClient: httpClientFunc(func(req *http.Request) (*http.Response, error) {
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body: io.NopCloser(strings.NewReader(`{
+					"output": {"message": {"role": "assistant", "content": [
+						{"toolUse": {"toolUseId": "t1", "name": "structured_output", "input": {"temp": 21}}}
+					]}},
+					"stopReason": "tool_use",
+					"usage": {"inputTokens": 10, "outputTokens": 5}
+				}`)),
+			}, nil
+		}),
+	})
+	m := NewModel(client, "us.amazon.nova-pro-v1:0", 0,
+		WithGenerateContentConfig(&genai.GenerateContentConfig{Temperature: genai.Ptr[float32](0.2)}))
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Weather in Paris?", genai.RoleUser)},
+		Config: &genai.GenerateContentConfig{
+			ResponseMIMEType: "application/json",
+			ResponseSchema: &genai.Schema{
+				Type:       genai.TypeObject,
+				Properties: map[string]*genai.Schema{"temp": {Type: genai.TypeNumber}},
+				Required:   []string{"temp"},
+			},
+		},
+	}
+	for resp, err := range m.GenerateContent(context.Background(), req, false) {
+		if err != nil {
+			t.Fatalf("GenerateContent() error = %v", err)
+		}
+		if text := resp.Content.Parts[0].Text; text != `{"temp":21}` {
+			t.Errorf("response = %q", text)
+		}
+	}
+
+	toolConfig, _ := body["toolConfig"].(map[string]any)
+	tools, _ := toolConfig["tools"].([]any)
+	if len(tools) != 1 || !strings.Contains(fmt.Sprint(tools[0]), "structured_output") {
+		t.Errorf("tools = %v, want the structured output tool", toolConfig["tools"])
+	}
+	if !strings.Contains(fmt.Sprint(toolConfig["toolChoice"]), "structured_output") {
+		t.Errorf("tool choice = %v, want the structured output tool", toolConfig["toolChoice"])
+	}
+	if inference, _ := body["inferenceConfig"].(map[string]any); inference["temperature"] == nil {
+		t.Errorf("inference config = %v, want the default temperature", body["inferenceConfig"])
+	}
+}
+
+func TestConvertRequestS3FileData(t *testing.T) {
+	m := NewModel(nil, "us.amazon.nova-pro-v1:0", 0, WithS3BucketOwner("111122223333")).(*bedrockModel)
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromParts([]*genai.Part{
+			genai.NewPartFromURI("s3://media/scans/invoice.pdf", "application/pdf"),
+			genai.NewPartFromURI("s3://media/cat.png", "image/png"),
+		}, genai.RoleUser)},
+	}
+	msgs, _, err := m.convertRequest(req)
+	if err != nil {
+		t.Fatalf("convertRequest() error = %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages", len(msgs))
+	}
+	doc, image := msgs[0], msgs[1]
+	if doc.Type != bedrockclient.MessageTypeDocument || doc.Name != "invoice.pdf" || doc.S3Location == nil ||
+		doc.S3Location.URI != "s3://media/scans/invoice.pdf" || doc.S3Location.BucketOwner != "111122223333" {
+		t.Errorf("document = %+v", doc)
+	}
+	if image.Type != "image" || image.MimeType != "image/png" || image.S3Location == nil {
+		t.Errorf("image = %+v", image)
+	}
+}
+
+// encodeTestImage returns a gray image of the given size.
+func encodeTestImage(t *testing.T, encode func(io.Writer, image.Image) error, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestConvertRequestNormalizesImages(t *testing.T) {
+	tiny := encodeTestImage(t, png.Encode, 1, 1)
+	var parts []*genai.Part
+	for range 20 {
+		parts = append(parts, genai.NewPartFromBytes(tiny, "image/png"))
+	}
+	parts = append(parts, genai.NewPartFromBytes(encodeTestImage(t, bmp.Encode, 9000, 10), "image/bmp"))
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromParts(parts, genai.RoleUser)}}
+
+	m := NewModel(nil, "us.anthropic.claude-sonnet-4-5-20250929-v1:0", 0, WithImageNormalization()).(*bedrockModel)
+	msgs, _, err := m.convertRequest(req)
+	if err != nil {
+		t.Fatalf("convertRequest() error = %v", err)
+	}
+	if len(msgs) != 21 || msgs[0].Type != "text" {
+		t.Fatalf("oldest image not replaced: %+v", msgs[0])
+	}
+	if msgs[1].Type != "image" || msgs[1].Content != string(tiny) {
+		t.Errorf("fitting image changed: %+v", msgs[1])
+	}
+	last := msgs[20]
+	if last.Type != "image" || last.MimeType != "image/jpeg" {
+		t.Fatalf("BMP not converted: type %q, MIME type %q", last.Type, last.MimeType)
+	}
+	config, err := jpeg.DecodeConfig(strings.NewReader(last.Content))
+	if err != nil || config.Width != 8000 {
+		t.Errorf("converted image: %+v, %v", config, err)
+	}
+	if req.Contents[0].Parts[20].InlineData.MIMEType != "image/bmp" {
+		t.Error("request contents were modified")
+	}
+}
+
+func TestConvertRequestNormalizesToolResultImages(t *testing.T) {
+	tiny := encodeTestImage(t, png.Encode, 1, 1)
+	var parts []*genai.FunctionResponsePart
+	for range 20 {
+		parts = append(parts, genai.NewFunctionResponsePartFromBytes(tiny, "image/png"))
+	}
+	parts = append(parts, genai.NewFunctionResponsePartFromBytes(encodeTestImage(t, bmp.Encode, 9000, 10), "image/bmp"))
+	resp := &genai.FunctionResponse{ID: "call_1", Name: "screenshots", Response: map[string]any{"output": "Captured"}, Parts: parts}
+	req := &model.LLMRequest{Contents: []*genai.Content{{Role: genai.RoleUser, Parts: []*genai.Part{{FunctionResponse: resp}}}}}
+
+	m := NewModel(nil, "us.anthropic.claude-sonnet-4-5-20250929-v1:0", 0, WithImageNormalization()).(*bedrockModel)
+	msgs, _, err := m.convertRequest(req)
+	if err != nil {
+		t.Fatalf("convertRequest() error = %v", err)
+	}
+	// The text of the result and the 20 latest images.
+	result := msgs[0]
+	if len(result.Parts) != 21 || result.Parts[0].Type != "text" {
+		t.Fatalf("got %d parts, want the oldest image dropped", len(result.Parts))
+	}
+	if last := result.Parts[20]; last.Type != "image" || last.MimeType != "image/jpeg" {
+		t.Errorf("BMP not converted: type %q, MIME type %q", last.Type, last.MimeType)
+	}
+	if len(resp.Parts) != 21 || resp.Parts[20].InlineData.MIMEType != "image/bmp" {
+		t.Error("request contents were modified")
+	}
+}
+
+func TestConvertRequestFunctionResponse(t *testing.T) {
+	m := NewModel(nil, "us.anthropic.claude-sonnet-4-5-20250929-v1:0", 0).(*bedrockModel)
+	screenshot := &genai.FunctionResponse{
+		ID:       "call_1",
+		Name:     "screenshot",
+		Response: map[string]any{"output": "Captured the page"},
+		Parts:    []*genai.FunctionResponsePart{genai.NewFunctionResponsePartFromBytes([]byte("PNG"), "image/png")},
+	}
+	weather := &genai.FunctionResponse{
+		ID:       "call_2",
+		Name:     "weather",
+		Response: map[string]any{"temp": 21},
+	}
+	failed := &genai.FunctionResponse{
+		ID:       "call_3",
+		Name:     "weather",
+		Response: map[string]any{"error": "city not found"},
+	}
+	// Error values that do not describe a failure.
+	var succeeded []*genai.Part
+	for _, value := range []any{0, map[string]any{}, "", false} {
+		succeeded = append(succeeded, &genai.Part{FunctionResponse: &genai.FunctionResponse{
+			ID:       "call_4",
+			Name:     "weather",
+			Response: map[string]any{"temp": 21, "error": value},
+		}})
+	}
+	failedObject := &genai.FunctionResponse{
+		ID:       "call_5",
+		Name:     "weather",
+		Response: map[string]any{"error": map[string]any{"code": 404}},
+	}
+	req := &model.LLMRequest{Contents: []*genai.Content{{
+		Role: genai.RoleUser,
+		Parts: append([]*genai.Part{{FunctionResponse: screenshot}, {FunctionResponse: weather}, {FunctionResponse: failed}},
+			append(succeeded, &genai.Part{FunctionResponse: failedObject})...),
+	}}}
+	msgs, _, err := m.convertRequest(req)
+	if err != nil {
+		t.Fatalf("convertRequest() error = %v", err)
+	}
+
+	result := msgs[0]
+	if result.Content != "Captured the page" || len(result.Parts) != 2 {
+		t.Fatalf("screenshot result = %+v", result)
+	}
+	if result.Parts[0].Type != "text" || result.Parts[1].Type != "image" || result.Parts[1].Content != "PNG" {
+		t.Errorf("screenshot parts = %+v", result.Parts)
+	}
+	if msgs[1].Content != `{"temp":21}` || msgs[1].Parts != nil || msgs[1].ToolError {
+		t.Errorf("weather result = %+v", msgs[1])
+	}
+	if msgs[2].Content != "city not found" || !msgs[2].ToolError {
+		t.Errorf("failed result = %+v", msgs[2])
+	}
+	for _, msg := range msgs[3:7] {
+		if msg.ToolError {
+			t.Errorf("result %s marked as an error", msg.Content)
+		}
+	}
+	if !msgs[7].ToolError {
+		t.Errorf("error object result = %+v", msgs[7])
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{}
+	for retry := 1; retry <= 10; retry++ {
+		d := p.backoff(retry)
+		if d <= 0 || d > 10*time.Second {
+			t.Errorf("backoff(%d) = %v, want within (0, 10s]", retry, d)
+		}
+	}
+}
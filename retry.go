@@ -0,0 +1,95 @@
+package adkgobedrock
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/dingdinglz/adk-go-bedrock/internal/bedrockclient"
+)
+
+// RetryPolicy controls how failed Bedrock calls are retried. Zero fields
+// fall back to their documented defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Default = 3
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It doubles on
+	// every further retry. Default = 500ms
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts. Default = 10s
+	MaxBackoff time.Duration
+	// Retryable reports whether an error is worth retrying.
+	// Default = IsRetryableError
+	Retryable func(error) bool
+}
+
+// IsRetryableError reports whether err is a transient Bedrock error, such
+// as throttling or a temporarily unavailable model.
+func IsRetryableError(err error) bool {
+	var (
+		throttling     *types.ThrottlingException
+		unavailable    *types.ServiceUnavailableException
+		notReady       *types.ModelNotReadyException
+		internal       *types.InternalServerException
+		modelTimeout   *types.ModelTimeoutException
+		modelStreamErr *types.ModelStreamErrorException
+		streamErr      *bedrockclient.StreamError
+	)
+	if errors.As(err, &streamErr) && streamErr.Retryable() {
+		return true
+	}
+	return errors.As(err, &throttling) ||
+		errors.As(err, &unavailable) ||
+		errors.As(err, &notReady) ||
+		errors.As(err, &internal) ||
+		errors.As(err, &modelTimeout) ||
+		errors.As(err, &modelStreamErr)
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return 3
+	}
+	return p.MaxAttempts
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryableError(err)
+}
+
+// backoff returns the jittered delay before the given retry, starting at 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = 500 * time.Millisecond
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 10 * time.Second
+	}
+
+	d := initial << (retry - 1)
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+	// Equal jitter in [d/2, d] spreads out concurrent retries.
+	return d/2 + rand.N(d/2+1)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}